@@ -0,0 +1,253 @@
+// Package attributes allows for storing attributes for a user across sessions.
+package attributes
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+
+	"github.com/go-alexa/alexa/events"
+	"github.com/go-alexa/alexa/parser"
+	"github.com/go-alexa/alexa/response"
+)
+
+var (
+	// ErrNoUser means there was no user ID to store attributes for.
+	ErrNoUser = errors.New("no user ID was provided for persistent attributes")
+)
+
+// Attributes are arbitrary data stored for a user.
+type Attributes map[string]interface{}
+
+// PersistenceAdapter loads and saves Attributes for users.
+type PersistenceAdapter interface {
+	// Load gets the Attributes for a user. If there are none, it returns an
+	// empty map and no error.
+	Load(ctx context.Context, userID string) (Attributes, error)
+	// Save stores all Attributes for a user, replacing any existing ones.
+	Save(ctx context.Context, userID string, attrs Attributes) error
+	// Delete removes all Attributes for a user.
+	Delete(ctx context.Context, userID string) error
+}
+
+// Manager gives access to a single user's persistent attributes. They are
+// loaded on first access and only saved if they were changed.
+type Manager struct {
+	adapter PersistenceAdapter
+	userID  string
+
+	mu     sync.Mutex
+	attrs  Attributes
+	loaded bool
+	dirty  bool
+}
+
+// NewManager creates a new Manager for a user.
+func NewManager(adapter PersistenceAdapter, userID string) *Manager {
+	return &Manager{
+		adapter: adapter,
+		userID:  userID,
+	}
+}
+
+// load loads the attributes if they have not been already. The lock must be
+// held.
+func (m *Manager) load(ctx context.Context) error {
+	if m.loaded {
+		return nil
+	}
+
+	if m.userID == "" {
+		return ErrNoUser
+	}
+
+	attrs, err := m.adapter.Load(ctx, m.userID)
+	if err != nil {
+		return err
+	}
+
+	if attrs == nil {
+		attrs = make(Attributes)
+	}
+
+	m.attrs = attrs
+	m.loaded = true
+
+	return nil
+}
+
+// Get gets a single attribute.
+func (m *Manager) Get(ctx context.Context, key string) (interface{}, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.load(ctx); err != nil {
+		return nil, err
+	}
+
+	return m.attrs[key], nil
+}
+
+// All gets a copy of all attributes.
+func (m *Manager) All(ctx context.Context) (Attributes, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.load(ctx); err != nil {
+		return nil, err
+	}
+
+	attrs := make(Attributes, len(m.attrs))
+	for k, v := range m.attrs {
+		attrs[k] = v
+	}
+
+	return attrs, nil
+}
+
+// Set sets a single attribute.
+func (m *Manager) Set(ctx context.Context, key string, value interface{}) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.load(ctx); err != nil {
+		return err
+	}
+
+	m.attrs[key] = value
+	m.dirty = true
+
+	return nil
+}
+
+// Remove removes a single attribute.
+func (m *Manager) Remove(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.load(ctx); err != nil {
+		return err
+	}
+
+	delete(m.attrs, key)
+	m.dirty = true
+
+	return nil
+}
+
+// Replace replaces all attributes without loading the existing ones.
+func (m *Manager) Replace(attrs Attributes) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if attrs == nil {
+		attrs = make(Attributes)
+	}
+
+	m.attrs = attrs
+	m.loaded = true
+	m.dirty = true
+}
+
+// Dirty is if the attributes were changed since they were last saved.
+func (m *Manager) Dirty() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.dirty
+}
+
+// Save saves the attributes if they were changed.
+func (m *Manager) Save(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.dirty {
+		return nil
+	}
+
+	if m.userID == "" {
+		return ErrNoUser
+	}
+
+	if err := m.adapter.Save(ctx, m.userID, m.attrs); err != nil {
+		return err
+	}
+
+	m.dirty = false
+
+	return nil
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying a Manager.
+func NewContext(ctx context.Context, m *Manager) context.Context {
+	return context.WithValue(ctx, contextKey{}, m)
+}
+
+// FromContext gets the Manager from a context. It is nil if there is none.
+func FromContext(ctx context.Context) *Manager {
+	m, _ := ctx.Value(contextKey{}).(*Manager)
+	return m
+}
+
+// Handler wraps an EventHandler so every event has a Manager for its user in
+// the context given to context handlers. Any changes are saved once the
+// event has been handled.
+type Handler struct {
+	events.EventHandler
+
+	Adapter PersistenceAdapter
+}
+
+// Wrap wraps an EventHandler to give it persistent attributes.
+func Wrap(h events.EventHandler, adapter PersistenceAdapter) *Handler {
+	return &Handler{
+		EventHandler: h,
+		Adapter:      adapter,
+	}
+}
+
+// Add adds a new intent handler to the wrapped EventHandler.
+func (h *Handler) Add(intent string, handler events.IntentFunc) events.EventHandler {
+	h.EventHandler.Add(intent, handler)
+
+	return h
+}
+
+// Event processes an event with the wrapped EventHandler.
+func (h *Handler) Event(ev *parser.Event) (*response.Response, error) {
+	return h.EventContext(context.Background(), ev)
+}
+
+// EventContext processes an event with the wrapped EventHandler, then saves
+// the attributes if they were changed. If the handler failed, even if its
+// ErrorHandler responded instead, any changes are discarded as they may be
+// incomplete.
+func (h *Handler) EventContext(ctx context.Context, ev *parser.Event) (*response.Response, error) {
+	m := NewManager(h.Adapter, ev.UserID())
+
+	// Errors the ErrorHandler responds to are still reported to ctx
+	var failed int32
+	handlerCtx := events.WithReporter(NewContext(ctx, m), func(ev *parser.Event, err error) {
+		atomic.StoreInt32(&failed, 1)
+		events.Report(ctx, ev, err)
+	})
+
+	resp, err := events.Process(handlerCtx, h.EventHandler, ev)
+	if err != nil {
+		return nil, err
+	}
+
+	if atomic.LoadInt32(&failed) != 0 {
+		return resp, nil
+	}
+
+	if err = m.Save(ctx); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
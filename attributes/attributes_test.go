@@ -0,0 +1,223 @@
+package attributes
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/go-alexa/alexa/events"
+	"github.com/go-alexa/alexa/parser"
+	"github.com/go-alexa/alexa/response"
+)
+
+// countingAdapter counts the calls to a PersistenceAdapter.
+type countingAdapter struct {
+	PersistenceAdapter
+
+	loads, saves int
+}
+
+func (a *countingAdapter) Load(ctx context.Context, userID string) (Attributes, error) {
+	a.loads++
+	return a.PersistenceAdapter.Load(ctx, userID)
+}
+
+func (a *countingAdapter) Save(ctx context.Context, userID string, attrs Attributes) error {
+	a.saves++
+	return a.PersistenceAdapter.Save(ctx, userID, attrs)
+}
+
+// userEvent creates an intent request event from a user.
+func userEvent(name string) *parser.Event {
+	return &parser.Event{
+		Session: parser.Session{User: parser.User{ID: "user"}},
+		Request: parser.Request{
+			Type:   events.RequestIntent,
+			Intent: parser.Intent{Name: name},
+		},
+	}
+}
+
+func TestHandlerLoadsLazilyAndSavesWhenDirty(t *testing.T) {
+	adapter := &countingAdapter{PersistenceAdapter: NewMemoryAdapter()}
+
+	h := events.New().
+		AddContext("Count", func(ctx context.Context, ev *parser.Event) (*response.Response, error) {
+			m := FromContext(ctx)
+
+			count, err := m.Get(ctx, "count")
+			if err != nil {
+				return nil, err
+			}
+
+			n, _ := count.(float64)
+			if err = m.Set(ctx, "count", n+1); err != nil {
+				return nil, err
+			}
+
+			return response.New(), nil
+		}).
+		AddContext("Read", func(ctx context.Context, ev *parser.Event) (*response.Response, error) {
+			_, err := FromContext(ctx).Get(ctx, "count")
+			return response.New(), err
+		}).
+		AddContext("Ignore", func(ctx context.Context, ev *parser.Event) (*response.Response, error) {
+			return response.New(), nil
+		})
+
+	wrapped := Wrap(h, adapter)
+
+	for _, tt := range []struct {
+		intent       string
+		loads, saves int
+	}{
+		{"Ignore", 0, 0},
+		{"Count", 1, 1},
+		{"Read", 2, 1},
+		{"Count", 3, 2},
+	} {
+		if _, err := wrapped.Event(userEvent(tt.intent)); err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.intent, err)
+		}
+
+		if adapter.loads != tt.loads || adapter.saves != tt.saves {
+			t.Errorf("%s: expected %d loads and %d saves, got %d and %d", tt.intent,
+				tt.loads, tt.saves, adapter.loads, adapter.saves)
+		}
+	}
+
+	attrs, err := adapter.Load(context.Background(), "user")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if attrs["count"] != float64(2) {
+		t.Errorf("expected count of 2, got %v", attrs["count"])
+	}
+}
+
+func TestAdapters(t *testing.T) {
+	dir := t.TempDir()
+
+	db, err := bolt.Open(filepath.Join(dir, "attributes.db"), 0600, nil)
+	if err != nil {
+		t.Fatalf("unable to open database: %v", err)
+	}
+	defer db.Close()
+
+	boltAdapter, err := NewBoltAdapter(db)
+	if err != nil {
+		t.Fatalf("unable to create bolt adapter: %v", err)
+	}
+
+	fileAdapter, err := NewFileAdapter(filepath.Join(dir, "files"))
+	if err != nil {
+		t.Fatalf("unable to create file adapter: %v", err)
+	}
+
+	for name, adapter := range map[string]PersistenceAdapter{
+		"memory": NewMemoryAdapter(),
+		"bolt":   boltAdapter,
+		"file":   fileAdapter,
+	} {
+		ctx := context.Background()
+
+		attrs, err := adapter.Load(ctx, "amzn1.ask.account.user")
+		if err != nil || len(attrs) != 0 {
+			t.Errorf("%s: expected no attributes, got %v, %v", name, attrs, err)
+		}
+
+		if err = adapter.Save(ctx, "amzn1.ask.account.user", Attributes{"name": "Alexa"}); err != nil {
+			t.Errorf("%s: unable to save: %v", name, err)
+		}
+
+		attrs, err = adapter.Load(ctx, "amzn1.ask.account.user")
+		if err != nil || attrs["name"] != "Alexa" {
+			t.Errorf("%s: expected saved attributes, got %v, %v", name, attrs, err)
+		}
+
+		if err = adapter.Delete(ctx, "amzn1.ask.account.user"); err != nil {
+			t.Errorf("%s: unable to delete: %v", name, err)
+		}
+
+		attrs, err = adapter.Load(ctx, "amzn1.ask.account.user")
+		if err != nil || len(attrs) != 0 {
+			t.Errorf("%s: expected deleted attributes, got %v, %v", name, attrs, err)
+		}
+	}
+}
+
+func TestHandlerDiscardsFailedChanges(t *testing.T) {
+	adapter := &countingAdapter{PersistenceAdapter: NewMemoryAdapter()}
+
+	set := func(ctx context.Context) {
+		if err := FromContext(ctx).Set(ctx, "name", "Alexa"); err != nil {
+			t.Fatalf("unable to set: %v", err)
+		}
+	}
+
+	h := events.New().
+		AddContext("Fails", func(ctx context.Context, ev *parser.Event) (*response.Response, error) {
+			set(ctx)
+			return nil, errors.New("failed")
+		}).
+		AddContext("Panics", func(ctx context.Context, ev *parser.Event) (*response.Response, error) {
+			set(ctx)
+			panic("oops")
+		})
+
+	var reported []error
+	ctx := events.WithReporter(context.Background(), func(ev *parser.Event, err error) {
+		reported = append(reported, err)
+	})
+
+	wrapped := Wrap(h, adapter)
+
+	for _, intent := range []string{"Fails", "Panics"} {
+		resp, err := wrapped.EventContext(ctx, userEvent(intent))
+		if err != nil || resp.Response.OutputSpeech.Text != events.ErrorSpeech {
+			t.Errorf("%s: expected the default apology, got %+v, %v", intent, resp, err)
+		}
+	}
+
+	if adapter.saves != 0 {
+		t.Errorf("expected no saves, got %d", adapter.saves)
+	}
+	if len(reported) != 2 {
+		t.Errorf("expected both errors to still be reported, got %v", reported)
+	}
+}
+
+func TestSaveWithoutUser(t *testing.T) {
+	adapter := &countingAdapter{PersistenceAdapter: NewMemoryAdapter()}
+
+	m := NewManager(adapter, "")
+	m.Replace(Attributes{"name": "Alexa"})
+
+	if err := m.Save(context.Background()); err != ErrNoUser {
+		t.Errorf("expected %v, got %v", ErrNoUser, err)
+	}
+	if adapter.saves != 0 {
+		t.Errorf("expected no saves, got %d", adapter.saves)
+	}
+}
+
+func TestBoltAdapterLoadError(t *testing.T) {
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "attributes.db"), 0600, nil)
+	if err != nil {
+		t.Fatalf("unable to open database: %v", err)
+	}
+
+	adapter, err := NewBoltAdapter(db)
+	if err != nil {
+		t.Fatalf("unable to create bolt adapter: %v", err)
+	}
+	db.Close()
+
+	if attrs, err := adapter.Load(context.Background(), "user"); err != bolt.ErrDatabaseNotOpen {
+		t.Errorf("expected %v, got %v, %v", bolt.ErrDatabaseNotOpen, attrs, err)
+	}
+}
@@ -0,0 +1,75 @@
+package attributes
+
+import (
+	"context"
+	"encoding/json"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var attributesBucket = []byte("attributes")
+
+// BoltAdapter is a PersistenceAdapter that stores attributes in a bolt
+// database.
+type BoltAdapter struct {
+	db *bolt.DB
+}
+
+// NewBoltAdapter creates a new BoltAdapter, creating its bucket if needed.
+func NewBoltAdapter(db *bolt.DB) (*BoltAdapter, error) {
+	err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(attributesBucket)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &BoltAdapter{db: db}, nil
+}
+
+// Load gets the Attributes for a user.
+func (a *BoltAdapter) Load(ctx context.Context, userID string) (Attributes, error) {
+	var data []byte
+
+	err := a.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(attributesBucket)
+
+		// The data is only valid during the transaction
+		if v := b.Get([]byte(userID)); v != nil {
+			data = append([]byte(nil), v...)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if data == nil {
+		return make(Attributes), nil
+	}
+
+	return decode(data)
+}
+
+// Save stores all Attributes for a user.
+func (a *BoltAdapter) Save(ctx context.Context, userID string, attrs Attributes) error {
+	data, err := json.Marshal(attrs)
+	if err != nil {
+		return err
+	}
+
+	return a.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(attributesBucket)
+		return b.Put([]byte(userID), data)
+	})
+}
+
+// Delete removes all Attributes for a user.
+func (a *BoltAdapter) Delete(ctx context.Context, userID string) error {
+	return a.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(attributesBucket)
+		return b.Delete([]byte(userID))
+	})
+}
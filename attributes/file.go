@@ -0,0 +1,81 @@
+package attributes
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// FileAdapter is a PersistenceAdapter that stores each user's attributes as
+// a JSON file in a directory.
+type FileAdapter struct {
+	dir string
+}
+
+// NewFileAdapter creates a new FileAdapter, creating the directory if needed.
+func NewFileAdapter(dir string) (*FileAdapter, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+
+	return &FileAdapter{dir: dir}, nil
+}
+
+// path gets the file for a user. User IDs are hashed as they are long and
+// may contain characters that are not allowed in file names.
+func (a *FileAdapter) path(userID string) string {
+	sum := sha256.Sum256([]byte(userID))
+	return filepath.Join(a.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+// Load gets the Attributes for a user.
+func (a *FileAdapter) Load(ctx context.Context, userID string) (Attributes, error) {
+	data, err := ioutil.ReadFile(a.path(userID))
+	if os.IsNotExist(err) {
+		return make(Attributes), nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return decode(data)
+}
+
+// Save stores all Attributes for a user. The file is replaced atomically so
+// it is never partially written.
+func (a *FileAdapter) Save(ctx context.Context, userID string, attrs Attributes) error {
+	data, err := json.Marshal(attrs)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(a.dir, ".attributes-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), a.path(userID))
+}
+
+// Delete removes all Attributes for a user.
+func (a *FileAdapter) Delete(ctx context.Context, userID string) error {
+	err := os.Remove(a.path(userID))
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	return err
+}
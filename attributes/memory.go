@@ -0,0 +1,67 @@
+package attributes
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+)
+
+// MemoryAdapter is a PersistenceAdapter that keeps attributes in memory. They
+// are lost when the process exits, so it is mostly useful for tests.
+type MemoryAdapter struct {
+	mu    sync.RWMutex
+	users map[string][]byte
+}
+
+// NewMemoryAdapter creates a new MemoryAdapter.
+func NewMemoryAdapter() *MemoryAdapter {
+	return &MemoryAdapter{
+		users: make(map[string][]byte),
+	}
+}
+
+// Load gets the Attributes for a user.
+func (a *MemoryAdapter) Load(ctx context.Context, userID string) (Attributes, error) {
+	a.mu.RLock()
+	data, ok := a.users[userID]
+	a.mu.RUnlock()
+
+	if !ok {
+		return make(Attributes), nil
+	}
+
+	return decode(data)
+}
+
+// Save stores all Attributes for a user.
+func (a *MemoryAdapter) Save(ctx context.Context, userID string, attrs Attributes) error {
+	data, err := json.Marshal(attrs)
+	if err != nil {
+		return err
+	}
+
+	a.mu.Lock()
+	a.users[userID] = data
+	a.mu.Unlock()
+
+	return nil
+}
+
+// Delete removes all Attributes for a user.
+func (a *MemoryAdapter) Delete(ctx context.Context, userID string) error {
+	a.mu.Lock()
+	delete(a.users, userID)
+	a.mu.Unlock()
+
+	return nil
+}
+
+// decode decodes stored attributes.
+func decode(data []byte) (Attributes, error) {
+	attrs := make(Attributes)
+	if err := json.Unmarshal(data, &attrs); err != nil {
+		return nil, err
+	}
+
+	return attrs, nil
+}
@@ -2,7 +2,10 @@
 package events
 
 import (
+	"context"
 	"errors"
+	"runtime/debug"
+	"strings"
 
 	"github.com/go-alexa/alexa/parser"
 	"github.com/go-alexa/alexa/response"
@@ -15,6 +18,62 @@ const (
 	RequestIntent = "IntentRequest"
 	// RequestEnded is when the session has ended.
 	RequestEnded = "SessionEndedRequest"
+	// RequestCanFulfillIntent is when Alexa asks if the skill can handle an
+	// intent.
+	RequestCanFulfillIntent = "CanFulfillIntentRequest"
+
+	// RequestPlaybackStarted is when the audio player has started playing.
+	RequestPlaybackStarted = "AudioPlayer.PlaybackStarted"
+	// RequestPlaybackFinished is when the audio player has finished playing.
+	RequestPlaybackFinished = "AudioPlayer.PlaybackFinished"
+	// RequestPlaybackStopped is when the audio player has stopped playing.
+	RequestPlaybackStopped = "AudioPlayer.PlaybackStopped"
+	// RequestPlaybackNearlyFinished is when the audio player is ready for the
+	// next stream.
+	RequestPlaybackNearlyFinished = "AudioPlayer.PlaybackNearlyFinished"
+	// RequestPlaybackFailed is when the audio player failed to play a stream.
+	RequestPlaybackFailed = "AudioPlayer.PlaybackFailed"
+
+	// RequestNextCommand is when the user pressed the next button.
+	RequestNextCommand = "PlaybackController.NextCommandIssued"
+	// RequestPauseCommand is when the user pressed the pause button.
+	RequestPauseCommand = "PlaybackController.PauseCommandIssued"
+	// RequestPlayCommand is when the user pressed the play button.
+	RequestPlayCommand = "PlaybackController.PlayCommandIssued"
+	// RequestPreviousCommand is when the user pressed the previous button.
+	RequestPreviousCommand = "PlaybackController.PreviousCommandIssued"
+
+	// RequestElementSelected is when the user selected an item on a display
+	// template.
+	RequestElementSelected = "Display.ElementSelected"
+	// RequestAPLUserEvent is when an APL document sent a user event.
+	RequestAPLUserEvent = "Alexa.Presentation.APL.UserEvent"
+	// RequestConnectionsResponse is the result of a Connections request, such
+	// as a purchase.
+	RequestConnectionsResponse = "Connections.Response"
+	// RequestMessageReceived is when a message was sent to the skill.
+	RequestMessageReceived = "Messaging.MessageReceived"
+	// RequestExceptionEncountered is when a previous response was invalid.
+	RequestExceptionEncountered = "System.ExceptionEncountered"
+
+	// RequestSkillEnabled is when a user enabled the skill.
+	RequestSkillEnabled = "AlexaSkillEvent.SkillEnabled"
+	// RequestSkillDisabled is when a user disabled the skill.
+	RequestSkillDisabled = "AlexaSkillEvent.SkillDisabled"
+	// RequestSkillAccountLinked is when a user linked their account.
+	RequestSkillAccountLinked = "AlexaSkillEvent.SkillAccountLinked"
+	// RequestSkillPermissionAccepted is when a user accepted permissions.
+	RequestSkillPermissionAccepted = "AlexaSkillEvent.SkillPermissionAccepted"
+	// RequestSkillPermissionChanged is when a user changed permissions.
+	RequestSkillPermissionChanged = "AlexaSkillEvent.SkillPermissionChanged"
+
+	// NamespaceAudioPlayer is the namespace of all AudioPlayer requests.
+	NamespaceAudioPlayer = "AudioPlayer"
+	// NamespacePlaybackController is the namespace of all PlaybackController
+	// requests.
+	NamespacePlaybackController = "PlaybackController"
+	// NamespaceSkillEvent is the namespace of all skill lifecycle requests.
+	NamespaceSkillEvent = "AlexaSkillEvent"
 )
 
 var (
@@ -31,24 +90,121 @@ type IntentFunc func(*parser.Event) (*response.Response, error)
 // EndedFunc is a func called when the session has ended.
 type EndedFunc func(*parser.Event) (*response.Response, error)
 
+// RequestFunc is a func called for any other type of request.
+type RequestFunc func(*parser.Event) (*response.Response, error)
+
+// LaunchContextFunc is the same as LaunchFunc, but is given the request's
+// context so it may stop once the context is done.
+type LaunchContextFunc func(context.Context, *parser.Event) (*response.Response, error)
+
+// IntentContextFunc is the same as IntentFunc, but is given the request's
+// context so it may stop once the context is done.
+type IntentContextFunc func(context.Context, *parser.Event) (*response.Response, error)
+
+// EndedContextFunc is the same as EndedFunc, but is given the request's
+// context so it may stop once the context is done.
+type EndedContextFunc func(context.Context, *parser.Event) (*response.Response, error)
+
+// RequestContextFunc is the same as RequestFunc, but is given the request's
+// context so it may stop once the context is done.
+type RequestContextFunc func(context.Context, *parser.Event) (*response.Response, error)
+
+// RequestInterceptor is a func called before the handler for every event, in
+// the order they were added. If it returns a response or an error, no further
+// interceptors or the handler are called.
+type RequestInterceptor func(*parser.Event) (*response.Response, error)
+
+// ResponseInterceptor is a func called after the handler for every event, in
+// the order they were added. It may modify the response, which is nil when
+// the handler did not respond. If it returns an error, no further
+// interceptors are called.
+type ResponseInterceptor func(*parser.Event, *response.Response) error
+
+// RequestContextInterceptor is the same as RequestInterceptor, but is given
+// the request's context so it may stop once the context is done.
+type RequestContextInterceptor func(context.Context, *parser.Event) (*response.Response, error)
+
+// ResponseContextInterceptor is the same as ResponseInterceptor, but is given
+// the request's context so it may stop once the context is done.
+type ResponseContextInterceptor func(context.Context, *parser.Event, *response.Response) error
+
 // EventHandler is a handler for any Alexa events.
 type EventHandler interface {
 	Add(string, IntentFunc) EventHandler
 	Event(*parser.Event) (*response.Response, error)
 }
 
-// Handler is a default implementation of the EventHandler.
+// ContextEventHandler is an EventHandler that is able to use the request's
+// context.
+type ContextEventHandler interface {
+	EventHandler
+
+	EventContext(context.Context, *parser.Event) (*response.Response, error)
+}
+
+// Process processes an event with an EventHandler. If it is a
+// ContextEventHandler, it is given ctx.
+func Process(ctx context.Context, h EventHandler, ev *parser.Event) (*response.Response, error) {
+	if ch, ok := h.(ContextEventHandler); ok {
+		return ch.EventContext(ctx, ev)
+	}
+
+	return h.Event(ev)
+}
+
+// Handler is a default implementation of the EventHandler. Context handlers
+// are used instead of the plain handlers when both are set.
+//
+// Request types other than launch, intent and session ended requests are
+// handled by RequestHandlers, keyed by either the request type or its
+// namespace, such as AudioPlayer. Any request type without a handler is given
+// to the CatchAllHandler.
+//
+// An intent without a handler is given to the FallbackHandler, and anything
+// else left unhandled to the UnhandledHandler. If either is nil, ErrNoHandler
+// is returned instead. Errors and panics are given to the ErrorHandler if it
+// is set, after they are reported to the ReportFunc of the context given to
+// EventContext, or logged.
 type Handler struct {
 	LaunchHandler LaunchFunc
 	EndedHandler  EndedFunc
 
-	IntentHandlers map[string]IntentFunc
+	LaunchContextHandler LaunchContextFunc
+	EndedContextHandler  EndedContextFunc
+
+	IntentHandlers        map[string]IntentFunc
+	IntentContextHandlers map[string]IntentContextFunc
+
+	RequestHandlers        map[string]RequestFunc
+	RequestContextHandlers map[string]RequestContextFunc
+
+	CatchAllHandler        RequestFunc
+	CatchAllContextHandler RequestContextFunc
+
+	FallbackHandler  IntentFunc
+	UnhandledHandler RequestFunc
+	ErrorHandler     ErrorFunc
+
+	// Interceptors added without a context are wrapped, so they are called
+	// in the order they were added alongside the context ones.
+	RequestInterceptors  []RequestContextInterceptor
+	ResponseInterceptors []ResponseContextInterceptor
 }
 
-// New creates a new Handler and initializes the map.
+// New creates a new Handler and initializes the maps. It uses
+// DefaultFallback, DefaultUnhandled and DefaultError so the user always
+// hears a response.
 func New() *Handler {
 	return &Handler{
-		IntentHandlers: make(map[string]IntentFunc),
+		IntentHandlers:        make(map[string]IntentFunc),
+		IntentContextHandlers: make(map[string]IntentContextFunc),
+
+		RequestHandlers:        make(map[string]RequestFunc),
+		RequestContextHandlers: make(map[string]RequestContextFunc),
+
+		FallbackHandler:  DefaultFallback,
+		UnhandledHandler: DefaultUnhandled,
+		ErrorHandler:     DefaultError,
 	}
 }
 
@@ -59,34 +215,246 @@ func (e *Handler) Add(intent string, handler IntentFunc) EventHandler {
 	return e
 }
 
+// AddContext adds a new context intent handler for a specific intent name.
+func (e *Handler) AddContext(intent string, handler IntentContextFunc) *Handler {
+	if e.IntentContextHandlers == nil {
+		e.IntentContextHandlers = make(map[string]IntentContextFunc)
+	}
+
+	e.IntentContextHandlers[intent] = handler
+
+	return e
+}
+
+// AddRequest adds a new handler for a request type, such as
+// RequestPlaybackStarted, or a namespace, such as NamespaceAudioPlayer.
+func (e *Handler) AddRequest(requestType string, handler RequestFunc) *Handler {
+	if e.RequestHandlers == nil {
+		e.RequestHandlers = make(map[string]RequestFunc)
+	}
+
+	e.RequestHandlers[requestType] = handler
+
+	return e
+}
+
+// AddRequestContext adds a new context handler for a request type or
+// namespace.
+func (e *Handler) AddRequestContext(requestType string, handler RequestContextFunc) *Handler {
+	if e.RequestContextHandlers == nil {
+		e.RequestContextHandlers = make(map[string]RequestContextFunc)
+	}
+
+	e.RequestContextHandlers[requestType] = handler
+
+	return e
+}
+
+// CatchAll sets the handler for any request type without a handler.
+func (e *Handler) CatchAll(handler RequestFunc) *Handler {
+	e.CatchAllHandler = handler
+
+	return e
+}
+
+// Fallback sets the handler for intents without a handler.
+func (e *Handler) Fallback(handler IntentFunc) *Handler {
+	e.FallbackHandler = handler
+
+	return e
+}
+
+// Unhandled sets the handler for any request nothing else handled.
+func (e *Handler) Unhandled(handler RequestFunc) *Handler {
+	e.UnhandledHandler = handler
+
+	return e
+}
+
+// OnError sets the handler for errors and panics.
+func (e *Handler) OnError(handler ErrorFunc) *Handler {
+	e.ErrorHandler = handler
+
+	return e
+}
+
+// AddRequestInterceptor adds interceptors to be called before the handler for
+// every event.
+func (e *Handler) AddRequestInterceptor(interceptors ...RequestInterceptor) *Handler {
+	for _, fn := range interceptors {
+		fn := fn
+		e.RequestInterceptors = append(e.RequestInterceptors,
+			func(ctx context.Context, ev *parser.Event) (*response.Response, error) {
+				return fn(ev)
+			})
+	}
+
+	return e
+}
+
+// AddRequestContextInterceptor is the same as AddRequestInterceptor, but the
+// interceptors are given the request's context.
+func (e *Handler) AddRequestContextInterceptor(interceptors ...RequestContextInterceptor) *Handler {
+	e.RequestInterceptors = append(e.RequestInterceptors, interceptors...)
+
+	return e
+}
+
+// AddResponseInterceptor adds interceptors to be called after the handler for
+// every event.
+func (e *Handler) AddResponseInterceptor(interceptors ...ResponseInterceptor) *Handler {
+	for _, fn := range interceptors {
+		fn := fn
+		e.ResponseInterceptors = append(e.ResponseInterceptors,
+			func(ctx context.Context, ev *parser.Event, resp *response.Response) error {
+				return fn(ev, resp)
+			})
+	}
+
+	return e
+}
+
+// AddResponseContextInterceptor is the same as AddResponseInterceptor, but the
+// interceptors are given the request's context.
+func (e *Handler) AddResponseContextInterceptor(interceptors ...ResponseContextInterceptor) *Handler {
+	e.ResponseInterceptors = append(e.ResponseInterceptors, interceptors...)
+
+	return e
+}
+
 // Event processes all event handlers for an event. It then returns the response
-// or any errors that occurred while processing.
+// or any errors that occurred while processing. Request interceptors are called
+// first and may respond instead of the handler. Response interceptors are then
+// called with whichever response was made.
 func (e *Handler) Event(ev *parser.Event) (*response.Response, error) {
-	var resp *response.Response
-	var err error
+	return e.EventContext(context.Background(), ev)
+}
+
+// EventContext is the same as Event, but gives ctx to any context handlers.
+func (e *Handler) EventContext(ctx context.Context, ev *parser.Event) (*response.Response, error) {
+	if e.ErrorHandler == nil {
+		return e.process(ctx, ev)
+	}
+
+	resp, err := e.recoverProcess(ctx, ev)
+	if err != nil {
+		Report(ctx, ev, err)
+		return e.ErrorHandler(ev, err)
+	}
+
+	return resp, nil
+}
+
+// recoverProcess processes an event, turning any panic into a *PanicError.
+func (e *Handler) recoverProcess(ctx context.Context, ev *parser.Event) (resp *response.Response, err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			resp, err = nil, &PanicError{
+				Value: v,
+				Stack: debug.Stack(),
+			}
+		}
+	}()
+
+	return e.process(ctx, ev)
+}
+
+// process calls the interceptors and handler for an event.
+func (e *Handler) process(ctx context.Context, ev *parser.Event) (*response.Response, error) {
+	resp, err := e.intercept(ctx, ev)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp == nil {
+		resp, err = e.dispatch(ctx, ev)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, fn := range e.ResponseInterceptors {
+		if err = fn(ctx, ev, resp); err != nil {
+			return nil, err
+		}
+	}
+
+	return resp, nil
+}
+
+// intercept calls the request interceptors until one responds or errors.
+func (e *Handler) intercept(ctx context.Context, ev *parser.Event) (*response.Response, error) {
+	for _, fn := range e.RequestInterceptors {
+		resp, err := fn(ctx, ev)
+		if resp != nil || err != nil {
+			return resp, err
+		}
+	}
+
+	return nil, nil
+}
 
+// dispatch calls the handler for an event.
+func (e *Handler) dispatch(ctx context.Context, ev *parser.Event) (*response.Response, error) {
 	switch ev.Request.Type {
 	case RequestLaunch:
-		if e.LaunchHandler != nil {
-			resp, err = e.LaunchHandler(ev)
-		} else {
-			err = ErrNoHandler
+		if e.LaunchContextHandler != nil {
+			return e.LaunchContextHandler(ctx, ev)
+		} else if e.LaunchHandler != nil {
+			return e.LaunchHandler(ev)
 		}
 
 	case RequestEnded:
-		if e.EndedHandler != nil {
-			resp, err = e.EndedHandler(ev)
-		} else {
-			err = ErrNoHandler
+		if e.EndedContextHandler != nil {
+			return e.EndedContextHandler(ctx, ev)
+		} else if e.EndedHandler != nil {
+			return e.EndedHandler(ev)
 		}
 
 	case RequestIntent:
-		if fn, ok := e.IntentHandlers[ev.Request.Intent.Name]; ok {
-			resp, err = fn(ev)
-		} else {
-			err = ErrNoHandler
+		if fn, ok := e.IntentContextHandlers[ev.Request.Intent.Name]; ok {
+			return fn(ctx, ev)
+		} else if fn, ok := e.IntentHandlers[ev.Request.Intent.Name]; ok {
+			return fn(ev)
+		} else if e.FallbackHandler != nil {
+			return e.FallbackHandler(ev)
+		}
+
+		return e.unhandled(ev)
+	}
+
+	for _, key := range []string{ev.Request.Type, namespace(ev.Request.Type)} {
+		if fn, ok := e.RequestContextHandlers[key]; ok {
+			return fn(ctx, ev)
+		} else if fn, ok := e.RequestHandlers[key]; ok {
+			return fn(ev)
 		}
 	}
 
-	return resp, err
+	if e.CatchAllContextHandler != nil {
+		return e.CatchAllContextHandler(ctx, ev)
+	} else if e.CatchAllHandler != nil {
+		return e.CatchAllHandler(ev)
+	}
+
+	return e.unhandled(ev)
+}
+
+// unhandled calls the UnhandledHandler for an event nothing else handled.
+func (e *Handler) unhandled(ev *parser.Event) (*response.Response, error) {
+	if e.UnhandledHandler != nil {
+		return e.UnhandledHandler(ev)
+	}
+
+	return nil, ErrNoHandler
+}
+
+// namespace gets the namespace of a request type, such as AudioPlayer for
+// AudioPlayer.PlaybackStarted.
+func namespace(requestType string) string {
+	if i := strings.LastIndex(requestType, "."); i > 0 {
+		return requestType[:i]
+	}
+
+	return requestType
 }
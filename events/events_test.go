@@ -0,0 +1,256 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/go-alexa/alexa/parser"
+	"github.com/go-alexa/alexa/response"
+)
+
+// intentEvent creates an intent request event for an intent name.
+func intentEvent(name string) *parser.Event {
+	return &parser.Event{
+		Request: parser.Request{
+			Type:   RequestIntent,
+			Intent: parser.Intent{Name: name},
+		},
+	}
+}
+
+func TestInterceptorOrder(t *testing.T) {
+	var calls []string
+
+	h := New().
+		AddRequestInterceptor(
+			func(ev *parser.Event) (*response.Response, error) {
+				calls = append(calls, "request 1")
+				return nil, nil
+			},
+			func(ev *parser.Event) (*response.Response, error) {
+				calls = append(calls, "request 2")
+				return nil, nil
+			}).
+		AddResponseInterceptor(
+			func(ev *parser.Event, resp *response.Response) error {
+				calls = append(calls, "response 1")
+				resp.AddReprompt("Anything else?")
+				return nil
+			},
+			func(ev *parser.Event, resp *response.Response) error {
+				calls = append(calls, "response 2")
+				return nil
+			})
+
+	h.Add("HelloWorld", func(ev *parser.Event) (*response.Response, error) {
+		calls = append(calls, "handler")
+		return response.New().AddSpeech("Hello, world!"), nil
+	})
+
+	resp, err := h.Event(intentEvent("HelloWorld"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []string{"request 1", "request 2", "handler", "response 1", "response 2"}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("expected calls %v, got %v", want, calls)
+	}
+
+	if resp.Response.Reprompt == nil {
+		t.Error("expected response interceptor to add a reprompt")
+	}
+}
+
+func TestContextInterceptors(t *testing.T) {
+	type key struct{}
+	var calls []string
+
+	h := New().
+		AddRequestInterceptor(func(ev *parser.Event) (*response.Response, error) {
+			calls = append(calls, "request")
+			return nil, nil
+		}).
+		AddRequestContextInterceptor(func(ctx context.Context, ev *parser.Event) (*response.Response, error) {
+			calls = append(calls, "request "+ctx.Value(key{}).(string))
+			return nil, nil
+		}).
+		AddResponseContextInterceptor(func(ctx context.Context, ev *parser.Event, resp *response.Response) error {
+			calls = append(calls, "response "+ctx.Value(key{}).(string))
+			return nil
+		}).
+		AddResponseInterceptor(func(ev *parser.Event, resp *response.Response) error {
+			calls = append(calls, "response")
+			return nil
+		})
+
+	h.Add("HelloWorld", func(ev *parser.Event) (*response.Response, error) {
+		return response.New(), nil
+	})
+
+	ctx := context.WithValue(context.Background(), key{}, "ctx")
+	if _, err := h.EventContext(ctx, intentEvent("HelloWorld")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []string{"request", "request ctx", "response ctx", "response"}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("expected calls %v, got %v", want, calls)
+	}
+}
+
+func TestRequestInterceptorShortCircuit(t *testing.T) {
+	errDenied := errors.New("denied")
+	handled := false
+
+	h := New().OnError(nil)
+	h.Add("HelloWorld", func(ev *parser.Event) (*response.Response, error) {
+		handled = true
+		return response.New(), nil
+	})
+
+	h.AddRequestInterceptor(func(ev *parser.Event) (*response.Response, error) {
+		if ev.Request.Intent.Name == "HelloWorld" {
+			return response.New().AddSpeech("Please link your account."), nil
+		}
+		return nil, errDenied
+	})
+
+	resp, err := h.Event(intentEvent("HelloWorld"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if handled {
+		t.Error("expected handler not to be called")
+	}
+	if got := resp.Response.OutputSpeech.Text; got != "Please link your account." {
+		t.Errorf("expected interceptor response, got %q", got)
+	}
+
+	if _, err := h.Event(intentEvent("Other")); err != errDenied {
+		t.Errorf("expected %v, got %v", errDenied, err)
+	}
+}
+
+func TestRequestTypeDispatch(t *testing.T) {
+	respond := func(speech string) RequestFunc {
+		return func(ev *parser.Event) (*response.Response, error) {
+			return response.New().AddSpeech(speech), nil
+		}
+	}
+
+	h := New().
+		AddRequest(RequestPlaybackStarted, respond("started")).
+		AddRequest(NamespaceAudioPlayer, respond("audio player")).
+		AddRequest(RequestAPLUserEvent, respond("apl")).
+		CatchAll(respond("catch all"))
+
+	for requestType, want := range map[string]string{
+		RequestPlaybackStarted:      "started",
+		RequestPlaybackStopped:      "audio player",
+		RequestAPLUserEvent:         "apl",
+		RequestSkillEnabled:         "catch all",
+		RequestLaunch:               "catch all",
+		"Some.Future.RequestType":   "catch all",
+		RequestExceptionEncountered: "catch all",
+	} {
+		resp, err := h.Event(&parser.Event{Request: parser.Request{Type: requestType}})
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", requestType, err)
+			continue
+		}
+
+		if got := resp.Response.OutputSpeech.Text; got != want {
+			t.Errorf("%s: expected %q, got %q", requestType, want, got)
+		}
+	}
+
+	if _, err := (&Handler{}).Event(&parser.Event{Request: parser.Request{Type: RequestPlaybackFailed}}); err != ErrNoHandler {
+		t.Errorf("expected %v without a handler, got %v", ErrNoHandler, err)
+	}
+}
+
+func TestDefaultHandlers(t *testing.T) {
+	h := New()
+	h.Add("Fails", func(ev *parser.Event) (*response.Response, error) {
+		return nil, errors.New("failed")
+	})
+	h.Add("Panics", func(ev *parser.Event) (*response.Response, error) {
+		panic("oops")
+	})
+
+	for _, tt := range []struct {
+		ev     *parser.Event
+		speech string
+	}{
+		{intentEvent("Unknown"), FallbackSpeech},
+		{intentEvent("Fails"), ErrorSpeech},
+		{intentEvent("Panics"), ErrorSpeech},
+		{&parser.Event{Request: parser.Request{Type: RequestLaunch}}, UnhandledSpeech},
+		{&parser.Event{Request: parser.Request{Type: RequestEnded}}, ""},
+	} {
+		resp, err := h.Event(tt.ev)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			continue
+		}
+
+		var got string
+		if resp.Response.OutputSpeech != nil {
+			got = resp.Response.OutputSpeech.Text
+		}
+
+		if got != tt.speech {
+			t.Errorf("expected speech %q, got %q", tt.speech, got)
+		}
+	}
+}
+
+func TestErrorHandlerGetsPanic(t *testing.T) {
+	var got error
+
+	h := New().
+		OnError(func(ev *parser.Event, err error) (*response.Response, error) {
+			got = err
+			return response.New().AddSpeech("sorry"), nil
+		})
+	h.Add("Panics", func(ev *parser.Event) (*response.Response, error) {
+		panic("oops")
+	})
+
+	if _, err := h.Event(intentEvent("Panics")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	perr, ok := got.(*PanicError)
+	if !ok {
+		t.Fatalf("expected a *PanicError, got %v", got)
+	}
+	if perr.Value != "oops" || len(perr.Stack) == 0 {
+		t.Errorf("expected panic value and stack, got %v", perr)
+	}
+}
+
+func TestErrorReportedToContext(t *testing.T) {
+	var reported error
+
+	h := New()
+	h.Add("Panics", func(ev *parser.Event) (*response.Response, error) {
+		panic("oops")
+	})
+
+	ctx := WithReporter(context.Background(), func(ev *parser.Event, err error) {
+		reported = err
+	})
+
+	resp, err := h.EventContext(ctx, intentEvent("Panics"))
+	if err != nil || resp.Response.OutputSpeech.Text != ErrorSpeech {
+		t.Fatalf("expected the default apology, got %+v, %v", resp, err)
+	}
+
+	if _, ok := reported.(*PanicError); !ok {
+		t.Errorf("expected the panic to be reported, got %v", reported)
+	}
+}
@@ -0,0 +1,93 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/go-alexa/alexa/parser"
+	"github.com/go-alexa/alexa/response"
+)
+
+const (
+	// FallbackSpeech is spoken by DefaultFallback.
+	FallbackSpeech = "Sorry, I didn't understand that. Please try again."
+	// ErrorSpeech is spoken by DefaultError.
+	ErrorSpeech = "Sorry, something went wrong. Please try again later."
+	// UnhandledSpeech is spoken by DefaultUnhandled.
+	UnhandledSpeech = "Sorry, I can't help with that."
+)
+
+// ErrorFunc is a func called when a handler or interceptor returned an error
+// or panicked. It may respond instead, or return an error.
+type ErrorFunc func(*parser.Event, error) (*response.Response, error)
+
+// PanicError is the error given to the ErrorHandler when a handler or
+// interceptor panicked.
+type PanicError struct {
+	// Value is the value the handler panicked with.
+	Value interface{}
+	// Stack is the stack trace of the panic.
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("events: handler panicked: %v", e.Value)
+}
+
+// ReportFunc is a func given every error and panic a Handler's ErrorHandler
+// handles, so they are still seen when the ErrorHandler responds instead.
+type ReportFunc func(*parser.Event, error)
+
+// reportKey is the context key of the ReportFunc.
+type reportKey struct{}
+
+// WithReporter gets a copy of ctx that makes a Handler give every error and
+// panic its ErrorHandler handles to fn. Without one, they are logged.
+func WithReporter(ctx context.Context, fn ReportFunc) context.Context {
+	return context.WithValue(ctx, reportKey{}, fn)
+}
+
+// Report gives an error to the ReportFunc in ctx, or logs it if there is none.
+// A wrapper that installs its own ReportFunc may use it to pass errors on to
+// the one it replaced.
+func Report(ctx context.Context, ev *parser.Event, err error) {
+	if fn, ok := ctx.Value(reportKey{}).(ReportFunc); ok && fn != nil {
+		fn(ev, err)
+		return
+	}
+
+	if perr, ok := err.(*PanicError); ok {
+		log.Printf("Request %s panicked: %v\n%s", ev.Request.ID, perr.Value, perr.Stack)
+	} else {
+		log.Printf("Request %s failed: %v", ev.Request.ID, err)
+	}
+}
+
+// DefaultFallback asks the user to try again when no intent handler matched.
+func DefaultFallback(ev *parser.Event) (*response.Response, error) {
+	return response.New().
+		AddSpeech(FallbackSpeech).
+		AddReprompt(FallbackSpeech).
+		KeepAlive(), nil
+}
+
+// DefaultError apologizes to the user. The error has already been reported
+// by the Handler, so it is not returned.
+func DefaultError(ev *parser.Event, err error) (*response.Response, error) {
+	return response.New().
+		AddSpeech(ErrorSpeech), nil
+}
+
+// DefaultUnhandled tells the user the skill is unable to help with launch and
+// intent requests. Any other request gets an empty response, as they are not
+// able to include speech.
+func DefaultUnhandled(ev *parser.Event) (*response.Response, error) {
+	switch ev.Request.Type {
+	case RequestLaunch, RequestIntent:
+		return response.New().
+			AddSpeech(UnhandledSpeech), nil
+	}
+
+	return response.New(), nil
+}
@@ -0,0 +1,111 @@
+package events
+
+import (
+	"context"
+
+	"github.com/go-alexa/alexa/parser"
+	"github.com/go-alexa/alexa/response"
+)
+
+// StateAttribute is the default session attribute the current state is stored
+// in.
+const StateAttribute = "state"
+
+// StateHandler is an EventHandler for multi-turn conversations. Each state has
+// its own Handler, so intents are routed by the pair of the current state and
+// the intent name. Intents without a handler in the current state are given
+// to that state's FallbackHandler.
+//
+// The current state is read from the session attributes. Handlers move to
+// another state with response.Transition, otherwise the conversation stays in
+// the current state.
+type StateHandler struct {
+	// Attribute is the session attribute the current state is stored in.
+	Attribute string
+	// States are the Handlers for each state. The initial state is "".
+	States map[string]*Handler
+}
+
+// NewStateHandler creates a new StateHandler with an initial state.
+func NewStateHandler() *StateHandler {
+	return &StateHandler{
+		Attribute: StateAttribute,
+		States: map[string]*Handler{
+			"": New(),
+		},
+	}
+}
+
+// State gets the Handler for a state, creating it if needed. It must not be
+// called while events are being processed.
+func (s *StateHandler) State(name string) *Handler {
+	if s.States == nil {
+		s.States = make(map[string]*Handler)
+	}
+
+	h, ok := s.States[name]
+	if !ok {
+		h = New()
+		s.States[name] = h
+	}
+
+	return h
+}
+
+// Add adds a new intent handler to the initial state.
+func (s *StateHandler) Add(intent string, handler IntentFunc) EventHandler {
+	s.State("").Add(intent, handler)
+
+	return s
+}
+
+// AddState adds a new intent handler for an intent in a state.
+func (s *StateHandler) AddState(state, intent string, handler IntentFunc) *StateHandler {
+	s.State(state).Add(intent, handler)
+
+	return s
+}
+
+// Current gets the current state of an event. Unknown states are treated as
+// the initial state.
+func (s *StateHandler) Current(ev *parser.Event) string {
+	state, _ := ev.Session.Attributes[s.Attribute].(string)
+	if _, ok := s.States[state]; !ok {
+		return ""
+	}
+
+	return state
+}
+
+// Event processes an event with the Handler for the current state.
+func (s *StateHandler) Event(ev *parser.Event) (*response.Response, error) {
+	return s.EventContext(context.Background(), ev)
+}
+
+// EventContext is the same as Event, but gives ctx to any context handlers.
+// If there is no Handler for the current state, it returns ErrNoHandler.
+func (s *StateHandler) EventContext(ctx context.Context, ev *parser.Event) (*response.Response, error) {
+	state := s.Current(ev)
+
+	// States is only read here, as events may be processed concurrently
+	h, ok := s.States[state]
+	if !ok {
+		return nil, ErrNoHandler
+	}
+
+	resp, err := h.EventContext(ctx, ev)
+	if err != nil || resp == nil {
+		return resp, err
+	}
+
+	if resp.NextState != nil {
+		state = *resp.NextState
+	}
+
+	if resp.Attributes == nil {
+		resp.Attributes = make(parser.SessionAttributes)
+	}
+	resp.Attributes[s.Attribute] = state
+
+	return resp, nil
+}
@@ -0,0 +1,93 @@
+package events
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/go-alexa/alexa/parser"
+	"github.com/go-alexa/alexa/response"
+)
+
+// stateEvent creates an intent request event in a state.
+func stateEvent(state, name string) *parser.Event {
+	ev := intentEvent(name)
+	ev.Session.Attributes = parser.SessionAttributes{StateAttribute: state}
+
+	return ev
+}
+
+func TestStateHandler(t *testing.T) {
+	respond := func(speech, next string) IntentFunc {
+		return func(ev *parser.Event) (*response.Response, error) {
+			resp := response.New().AddSpeech(speech)
+			if next != "-" {
+				resp.Transition(next)
+			}
+			return resp, nil
+		}
+	}
+
+	h := NewStateHandler()
+	h.Add("StartGame", respond("Ready?", "playing"))
+	h.AddState("playing", "Guess", respond("Guessed", "-"))
+	h.AddState("playing", "Stop", respond("Bye", ""))
+	h.State("playing").Fallback(respond("Please guess", "-"))
+
+	for _, tt := range []struct {
+		ev     *parser.Event
+		speech string
+		state  string
+	}{
+		{intentEvent("StartGame"), "Ready?", "playing"},
+		{stateEvent("playing", "Guess"), "Guessed", "playing"},
+		{stateEvent("playing", "StartGame"), "Please guess", "playing"},
+		{stateEvent("playing", "Stop"), "Bye", ""},
+		{stateEvent("unknown", "StartGame"), "Ready?", "playing"},
+		{intentEvent("Guess"), FallbackSpeech, ""},
+	} {
+		resp, err := h.Event(tt.ev)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			continue
+		}
+
+		if got := resp.Response.OutputSpeech.Text; got != tt.speech {
+			t.Errorf("expected speech %q, got %q", tt.speech, got)
+		}
+
+		if got := resp.Attributes[StateAttribute]; got != tt.state {
+			t.Errorf("expected state %q, got %q", tt.state, got)
+		}
+	}
+}
+
+func TestStateHandlerWithoutInitialState(t *testing.T) {
+	var h StateHandler
+
+	// Events are processed concurrently without changing States
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+
+	for i := 0; i < cap(errs); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			_, err := h.Event(intentEvent("HelloWorld"))
+			errs <- err
+		}()
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != ErrNoHandler {
+			t.Errorf("expected %v, got %v", ErrNoHandler, err)
+		}
+	}
+
+	if h.States != nil {
+		t.Errorf("expected States not to be created, got %v", h.States)
+	}
+}
@@ -0,0 +1,147 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+// TestParseGolden parses each sample request and compares it, encoded again,
+// to its golden file. Run with -update to write the golden files.
+func TestParseGolden(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "*.json"))
+	if err != nil || len(files) == 0 {
+		t.Fatalf("unable to find samples: %v", err)
+	}
+
+	for _, file := range files {
+		sample, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatalf("unable to read %s: %v", file, err)
+		}
+
+		ev, err := Parse(sample)
+		if err != nil {
+			t.Errorf("%s: unable to parse: %v", file, err)
+			continue
+		}
+
+		got, err := json.MarshalIndent(ev, "", "\t")
+		if err != nil {
+			t.Errorf("%s: unable to encode: %v", file, err)
+			continue
+		}
+		got = append(got, '\n')
+
+		golden := strings.TrimSuffix(file, ".json") + ".golden"
+		if *update {
+			if err = ioutil.WriteFile(golden, got, 0644); err != nil {
+				t.Fatalf("unable to write %s: %v", golden, err)
+			}
+		}
+
+		want, err := ioutil.ReadFile(golden)
+		if err != nil {
+			t.Fatalf("unable to read %s: %v", golden, err)
+		}
+
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s: does not match %s, got:\n%s", file, golden, got)
+		}
+	}
+}
+
+// TestParseContextComplete ensures nothing in the context or intent of each
+// sample request is lost when it is parsed.
+func TestParseContextComplete(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "*.json"))
+	if err != nil || len(files) == 0 {
+		t.Fatalf("unable to find samples: %v", err)
+	}
+
+	for _, file := range files {
+		sample, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatalf("unable to read %s: %v", file, err)
+		}
+
+		ev, err := Parse(sample)
+		if err != nil {
+			t.Errorf("%s: unable to parse: %v", file, err)
+			continue
+		}
+
+		var want struct {
+			Context interface{} `json:"context"`
+			Request struct {
+				Intent interface{} `json:"intent"`
+			} `json:"request"`
+		}
+		if err = json.Unmarshal(sample, &want); err != nil {
+			t.Fatalf("unable to decode %s: %v", file, err)
+		}
+
+		for _, part := range []struct {
+			name string
+			want interface{}
+			got  interface{}
+		}{
+			{"context", want.Context, ev.Context},
+			{"request.intent", want.Request.Intent, ev.Request.Intent},
+		} {
+			encoded, err := json.Marshal(part.got)
+			if err != nil {
+				t.Errorf("%s: unable to encode %s: %v", file, part.name, err)
+				continue
+			}
+
+			var got interface{}
+			json.Unmarshal(encoded, &got)
+
+			contains(t, file+": "+part.name, part.want, got)
+		}
+	}
+}
+
+// contains reports anything in want that is not in got. Zero values may be
+// missing from got, as they are omitted when encoded.
+func contains(t *testing.T, path string, want, got interface{}) {
+	t.Helper()
+
+	switch want := want.(type) {
+	case nil:
+		return
+	case map[string]interface{}:
+		got, _ := got.(map[string]interface{})
+		for key, value := range want {
+			contains(t, path+"."+key, value, got[key])
+		}
+	case []interface{}:
+		got, _ := got.([]interface{})
+		if len(want) == 0 {
+			return
+		}
+		if len(got) != len(want) {
+			t.Errorf("%s: expected %d values, got %d", path, len(want), len(got))
+			return
+		}
+		for i := range want {
+			contains(t, fmt.Sprintf("%s[%d]", path, i), want[i], got[i])
+		}
+	default:
+		if got == nil && (want == false || want == float64(0) || want == "") {
+			return
+		}
+		if !reflect.DeepEqual(want, got) {
+			t.Errorf("%s: expected %v, got %v", path, want, got)
+		}
+	}
+}
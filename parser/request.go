@@ -0,0 +1,186 @@
+package parser
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// RequestBody is the part of a request specific to its type. Use a type
+// switch to get the fields for each type, such as *IntentRequest or
+// *AudioPlayerRequest.
+type RequestBody interface {
+	requestBody()
+}
+
+// LaunchRequest is the body of a LaunchRequest. It has no fields of its own.
+type LaunchRequest struct{}
+
+// IntentRequest is the body of an IntentRequest.
+type IntentRequest struct {
+	DialogState string `json:"dialogState,omitempty"`
+	Intent      Intent `json:"intent"`
+}
+
+// CanFulfillIntentRequest is the body of a CanFulfillIntentRequest.
+type CanFulfillIntentRequest struct {
+	DialogState string `json:"dialogState,omitempty"`
+	Intent      Intent `json:"intent"`
+}
+
+// SessionEndedRequest is the body of a SessionEndedRequest. Error is set when
+// the Reason is ERROR.
+type SessionEndedRequest struct {
+	Reason string        `json:"reason"`
+	Error  *RequestError `json:"error,omitempty"`
+}
+
+// RequestError is an error sent by Alexa, such as why a session ended or
+// audio failed to play.
+type RequestError struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+// AudioPlayerRequest is the body of any AudioPlayer request. Error and
+// CurrentPlaybackState are only set for AudioPlayer.PlaybackFailed.
+type AudioPlayerRequest struct {
+	Token                string        `json:"token,omitempty"`
+	OffsetInMilliseconds int64         `json:"offsetInMilliseconds"`
+	Error                *RequestError `json:"error,omitempty"`
+	CurrentPlaybackState *AudioPlayer  `json:"currentPlaybackState,omitempty"`
+}
+
+// PlaybackControllerRequest is the body of any PlaybackController request. It
+// has no fields of its own.
+type PlaybackControllerRequest struct{}
+
+// ElementSelectedRequest is the body of a Display.ElementSelected request.
+type ElementSelectedRequest struct {
+	Token string `json:"token"`
+}
+
+// APLUserEventRequest is the body of an Alexa.Presentation.APL.UserEvent
+// request.
+type APLUserEventRequest struct {
+	Token      string                     `json:"token"`
+	Arguments  []json.RawMessage          `json:"arguments,omitempty"`
+	Source     APLEventSource             `json:"source"`
+	Components map[string]json.RawMessage `json:"components,omitempty"`
+}
+
+// APLEventSource is the APL component that sent a user event.
+type APLEventSource struct {
+	Type    string          `json:"type"`
+	Handler string          `json:"handler"`
+	ID      string          `json:"id,omitempty"`
+	Value   json.RawMessage `json:"value,omitempty"`
+}
+
+// ConnectionsResponseRequest is the body of a Connections.Response request.
+type ConnectionsResponseRequest struct {
+	Name    string            `json:"name"`
+	Status  ConnectionsStatus `json:"status"`
+	Token   string            `json:"token,omitempty"`
+	Payload json.RawMessage   `json:"payload,omitempty"`
+}
+
+// ConnectionsStatus is the result of a Connections request. Code is an HTTP
+// status code, such as "200".
+type ConnectionsStatus struct {
+	Code    string `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+// MessageReceivedRequest is the body of a Messaging.MessageReceived request.
+type MessageReceivedRequest struct {
+	Message json.RawMessage `json:"message"`
+}
+
+// ExceptionEncounteredRequest is the body of a System.ExceptionEncountered
+// request.
+type ExceptionEncounteredRequest struct {
+	Error RequestError `json:"error"`
+	Cause struct {
+		RequestID string `json:"requestId"`
+	} `json:"cause"`
+}
+
+// SkillEventRequest is the body of any AlexaSkillEvent request. Body depends
+// on the event, such as the permissions accepted.
+type SkillEventRequest struct {
+	EventCreationTime   *Time           `json:"eventCreationTime,omitempty"`
+	EventPublishingTime *Time           `json:"eventPublishingTime,omitempty"`
+	Body                json.RawMessage `json:"body,omitempty"`
+}
+
+func (*LaunchRequest) requestBody()               {}
+func (*IntentRequest) requestBody()               {}
+func (*CanFulfillIntentRequest) requestBody()     {}
+func (*SessionEndedRequest) requestBody()         {}
+func (*AudioPlayerRequest) requestBody()          {}
+func (*PlaybackControllerRequest) requestBody()   {}
+func (*ElementSelectedRequest) requestBody()      {}
+func (*APLUserEventRequest) requestBody()         {}
+func (*ConnectionsResponseRequest) requestBody()  {}
+func (*MessageReceivedRequest) requestBody()      {}
+func (*ExceptionEncounteredRequest) requestBody() {}
+func (*SkillEventRequest) requestBody()           {}
+
+// requestBodies creates the body for each request type.
+var requestBodies = map[string]func() RequestBody{
+	"LaunchRequest":                    func() RequestBody { return &LaunchRequest{} },
+	"IntentRequest":                    func() RequestBody { return &IntentRequest{} },
+	"CanFulfillIntentRequest":          func() RequestBody { return &CanFulfillIntentRequest{} },
+	"SessionEndedRequest":              func() RequestBody { return &SessionEndedRequest{} },
+	"Display.ElementSelected":          func() RequestBody { return &ElementSelectedRequest{} },
+	"Alexa.Presentation.APL.UserEvent": func() RequestBody { return &APLUserEventRequest{} },
+	"Connections.Response":             func() RequestBody { return &ConnectionsResponseRequest{} },
+	"Messaging.MessageReceived":        func() RequestBody { return &MessageReceivedRequest{} },
+	"System.ExceptionEncountered":      func() RequestBody { return &ExceptionEncounteredRequest{} },
+}
+
+// namespaceBodies creates the body for every request type in a namespace.
+var namespaceBodies = map[string]func() RequestBody{
+	"AudioPlayer":        func() RequestBody { return &AudioPlayerRequest{} },
+	"PlaybackController": func() RequestBody { return &PlaybackControllerRequest{} },
+	"AlexaSkillEvent":    func() RequestBody { return &SkillEventRequest{} },
+}
+
+// UnmarshalJSON decodes the common fields of a request, then its Body for its
+// type. The JSON is kept in Raw.
+func (r *Request) UnmarshalJSON(b []byte) error {
+	// plainRequest has no methods, so it is decoded normally
+	type plainRequest Request
+
+	var req plainRequest
+	if err := json.Unmarshal(b, &req); err != nil {
+		return err
+	}
+
+	*r = Request(req)
+	r.Raw = append(json.RawMessage(nil), b...)
+
+	newBody, ok := requestBodies[r.Type]
+	if !ok {
+		newBody, ok = namespaceBodies[strings.SplitN(r.Type, ".", 2)[0]]
+	}
+
+	if !ok {
+		return nil
+	}
+
+	body := newBody()
+	if err := json.Unmarshal(b, body); err != nil {
+		return err
+	}
+
+	r.Body = body
+
+	return nil
+}
+
+// Decode decodes the request into v. It may be used for request types that
+// have no Body.
+func (r *Request) Decode(v interface{}) error {
+	return json.Unmarshal(r.Raw, v)
+}
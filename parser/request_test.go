@@ -0,0 +1,132 @@
+package parser
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestRequestBody(t *testing.T) {
+	for _, tt := range []struct {
+		request string
+		want    RequestBody
+	}{
+		{
+			`{"type": "LaunchRequest"}`,
+			&LaunchRequest{},
+		},
+		{
+			`{"type": "IntentRequest", "dialogState": "IN_PROGRESS", "intent": {"name": "Order"}}`,
+			&IntentRequest{DialogState: "IN_PROGRESS", Intent: Intent{Name: "Order"}},
+		},
+		{
+			`{"type": "SessionEndedRequest", "reason": "ERROR", "error": {"type": "INVALID_RESPONSE", "message": "bad"}}`,
+			&SessionEndedRequest{Reason: "ERROR", Error: &RequestError{Type: "INVALID_RESPONSE", Message: "bad"}},
+		},
+		{
+			`{"type": "AudioPlayer.PlaybackStopped", "token": "track", "offsetInMilliseconds": 1000}`,
+			&AudioPlayerRequest{Token: "track", OffsetInMilliseconds: 1000},
+		},
+		{
+			`{"type": "AudioPlayer.PlaybackFailed", "token": "track",
+				"error": {"type": "MEDIA_ERROR_UNKNOWN", "message": "failed"},
+				"currentPlaybackState": {"token": "track", "offsetInMilliseconds": 5, "playerActivity": "PLAYING"}}`,
+			&AudioPlayerRequest{
+				Token:                "track",
+				Error:                &RequestError{Type: "MEDIA_ERROR_UNKNOWN", Message: "failed"},
+				CurrentPlaybackState: &AudioPlayer{Token: "track", OffsetInMilliseconds: 5, PlayerActivity: "PLAYING"},
+			},
+		},
+		{
+			`{"type": "PlaybackController.NextCommandIssued"}`,
+			&PlaybackControllerRequest{},
+		},
+		{
+			`{"type": "Display.ElementSelected", "token": "item"}`,
+			&ElementSelectedRequest{Token: "item"},
+		},
+		{
+			`{"type": "Alexa.Presentation.APL.UserEvent", "token": "doc", "arguments": ["next", 1],
+				"source": {"type": "TouchWrapper", "handler": "Press", "id": "button"}}`,
+			&APLUserEventRequest{
+				Token:     "doc",
+				Arguments: []json.RawMessage{json.RawMessage(`"next"`), json.RawMessage(`1`)},
+				Source:    APLEventSource{Type: "TouchWrapper", Handler: "Press", ID: "button"},
+			},
+		},
+		{
+			`{"type": "Connections.Response", "name": "Buy", "status": {"code": "200", "message": "OK"},
+				"token": "purchase", "payload": {"purchaseResult": "ACCEPTED"}}`,
+			&ConnectionsResponseRequest{
+				Name:    "Buy",
+				Status:  ConnectionsStatus{Code: "200", Message: "OK"},
+				Token:   "purchase",
+				Payload: json.RawMessage(`{"purchaseResult": "ACCEPTED"}`),
+			},
+		},
+		{
+			`{"type": "Messaging.MessageReceived", "message": {"text": "hi"}}`,
+			&MessageReceivedRequest{Message: json.RawMessage(`{"text": "hi"}`)},
+		},
+		{
+			`{"type": "AlexaSkillEvent.SkillEnabled", "body": {}}`,
+			&SkillEventRequest{Body: json.RawMessage(`{}`)},
+		},
+		{
+			`{"type": "Unknown.Request"}`,
+			nil,
+		},
+	} {
+		var r Request
+		if err := json.Unmarshal([]byte(tt.request), &r); err != nil {
+			t.Errorf("unable to decode %s: %v", tt.request, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(r.Body, tt.want) {
+			t.Errorf("%s: expected %#v, got %#v", r.Type, tt.want, r.Body)
+		}
+
+		if string(r.Raw) != tt.request {
+			t.Errorf("%s: expected the raw request to be kept", r.Type)
+		}
+	}
+}
+
+func TestRequestCommonFields(t *testing.T) {
+	ev, err := Parse([]byte(`{
+		"request": {
+			"type": "System.ExceptionEncountered",
+			"requestId": "request",
+			"locale": "en-US",
+			"error": {"type": "INVALID_RESPONSE", "message": "bad"},
+			"cause": {"requestId": "previous"}
+		}
+	}`))
+	if err != nil {
+		t.Fatalf("unable to parse: %v", err)
+	}
+
+	if ev.Request.ID != "request" || ev.Request.Locale != "en-US" {
+		t.Errorf("expected the common fields, got %+v", ev.Request)
+	}
+
+	body, ok := ev.Request.Body.(*ExceptionEncounteredRequest)
+	if !ok {
+		t.Fatalf("expected an *ExceptionEncounteredRequest, got %T", ev.Request.Body)
+	}
+
+	if body.Error.Type != "INVALID_RESPONSE" || body.Cause.RequestID != "previous" {
+		t.Errorf("expected the exception, got %+v", body)
+	}
+
+	var custom struct {
+		Cause struct {
+			RequestID string `json:"requestId"`
+		} `json:"cause"`
+	}
+
+	if err = ev.Request.Decode(&custom); err != nil || custom.Cause.RequestID != "previous" {
+		t.Errorf("expected to decode the raw request, got %+v, %v", custom, err)
+	}
+}
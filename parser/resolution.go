@@ -0,0 +1,212 @@
+package parser
+
+import (
+	"errors"
+	"strings"
+)
+
+// Status codes of a Resolution.
+const (
+	// ResolutionMatch means the value matched an entity.
+	ResolutionMatch = "ER_SUCCESS_MATCH"
+	// ResolutionNoMatch means the value did not match any entity.
+	ResolutionNoMatch = "ER_SUCCESS_NO_MATCH"
+	// ResolutionTimeout means the authority took too long to resolve the value.
+	ResolutionTimeout = "ER_ERROR_TIMEOUT"
+	// ResolutionException means the authority failed to resolve the value.
+	ResolutionException = "ER_ERROR_EXCEPTION"
+)
+
+// dynamicAuthority is the prefix of the authority for dynamic entities.
+const dynamicAuthority = "amzn1.er-authority.echo-sdk.dynamic"
+
+var (
+	// ErrNoResolutions means the slot has no entity resolutions, such as a
+	// built-in slot type or a slot that was not filled.
+	ErrNoResolutions = errors.New("slot has no entity resolutions")
+	// ErrNoMatch means the value did not match any entity.
+	ErrNoMatch = errors.New("slot value did not match any entity")
+	// ErrResolutionTimeout means resolving the value timed out, so it may
+	// match an entity that was not found.
+	ErrResolutionTimeout = errors.New("entity resolution timed out")
+	// ErrResolutionFailed means resolving the value failed.
+	ErrResolutionFailed = errors.New("entity resolution failed")
+)
+
+// ResolvedValue is an entity a slot value matched.
+type ResolvedValue struct {
+	// ID is the ID of the entity.
+	ID string
+	// Name is the canonical name of the entity.
+	Name string
+	// Authority is the authority that matched it.
+	Authority string
+}
+
+// Dynamic is if the value matched a dynamic entity instead of one in the
+// skill's static catalog.
+func (v ResolvedValue) Dynamic() bool {
+	return isDynamic(v.Authority)
+}
+
+// isDynamic is if an authority is for dynamic entities.
+func isDynamic(authority string) bool {
+	return strings.HasPrefix(authority, dynamicAuthority)
+}
+
+// Matches gets every entity the value matched, with dynamic entities first as
+// they are more specific to the user. Within an authority, values are in
+// Alexa's order, best first.
+func (r Resolutions) Matches() []ResolvedValue {
+	var dynamic, static []ResolvedValue
+
+	for _, res := range r.ResolutionsPerAuthority {
+		if res.Status.Code != ResolutionMatch {
+			continue
+		}
+
+		for _, v := range res.Values {
+			value := ResolvedValue{
+				ID:        v.Value.ID,
+				Name:      v.Value.Name,
+				Authority: res.Authority,
+			}
+
+			if isDynamic(res.Authority) {
+				dynamic = append(dynamic, value)
+			} else {
+				static = append(static, value)
+			}
+		}
+	}
+
+	return append(dynamic, static...)
+}
+
+// Resolved gets the best entity the value matched. If there is none, the
+// error is ErrNoResolutions, ErrNoMatch, ErrResolutionTimeout, or
+// ErrResolutionFailed. A timeout is reported before a failure, and both before
+// no match, as the value may have matched if it had been resolved.
+func (r Resolutions) Resolved() (*ResolvedValue, error) {
+	if matches := r.Matches(); len(matches) > 0 {
+		return &matches[0], nil
+	}
+
+	return nil, r.err()
+}
+
+// err gets why there were no matches.
+func (r Resolutions) err() error {
+	if len(r.ResolutionsPerAuthority) == 0 {
+		return ErrNoResolutions
+	}
+
+	err := ErrNoMatch
+
+	for _, res := range r.ResolutionsPerAuthority {
+		switch res.Status.Code {
+		case ResolutionTimeout:
+			return ErrResolutionTimeout
+		case ResolutionException:
+			err = ErrResolutionFailed
+		}
+	}
+
+	return err
+}
+
+// Matches gets every entity the value matched.
+func (v SlotValue) Matches() []ResolvedValue {
+	return v.Resolutions.Matches()
+}
+
+// Resolved gets the best entity the value matched.
+func (v SlotValue) Resolved() (*ResolvedValue, error) {
+	return v.Resolutions.Resolved()
+}
+
+// ResolvedID gets the ID of the best entity the value matched, or nothing if
+// it did not match one.
+func (v SlotValue) ResolvedID() string {
+	if r, err := v.Resolved(); err == nil {
+		return r.ID
+	}
+
+	return ""
+}
+
+// Matches gets every entity the slot's value matched. For a slot with
+// multiple values, it is the first value's, so use Values for each of them.
+func (s Slot) Matches() []ResolvedValue {
+	values := s.Values()
+	if len(values) == 0 {
+		return nil
+	}
+
+	return values[0].Matches()
+}
+
+// Resolved gets the best entity the slot's value matched. For a slot with
+// multiple values, it is the first value's, so use ResolvedValues for each of
+// them.
+func (s Slot) Resolved() (*ResolvedValue, error) {
+	values := s.Values()
+	if len(values) == 0 {
+		return nil, ErrNoResolutions
+	}
+
+	return values[0].Resolved()
+}
+
+// ResolvedID gets the ID of the best entity the slot's value matched, or
+// nothing if it did not match one.
+func (s Slot) ResolvedID() string {
+	if v, err := s.Resolved(); err == nil {
+		return v.ID
+	}
+
+	return ""
+}
+
+// ResolvedValues gets the best entity each of the slot's values matched, in
+// the same order as Values. A value that did not match one is nil, and err is
+// why the first such value did not.
+func (s Slot) ResolvedValues() ([]*ResolvedValue, error) {
+	values := s.Values()
+	if len(values) == 0 {
+		return nil, ErrNoResolutions
+	}
+
+	var err error
+
+	resolved := make([]*ResolvedValue, len(values))
+	for i, v := range values {
+		r, verr := v.Resolved()
+		if verr != nil && err == nil {
+			err = verr
+		}
+
+		resolved[i] = r
+	}
+
+	return resolved, err
+}
+
+// ResolvedIDs gets the ID of the best entity each of the slot's values
+// matched, in the same order as Values. A value that did not match one has no
+// ID.
+func (s Slot) ResolvedIDs() []string {
+	resolved, _ := s.ResolvedValues()
+	if len(resolved) == 0 {
+		return nil
+	}
+
+	ids := make([]string, len(resolved))
+	for i, r := range resolved {
+		if r != nil {
+			ids[i] = r.ID
+		}
+	}
+
+	return ids
+}
@@ -0,0 +1,102 @@
+package parser
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// resolutionSlot creates a slot with a resolution for each authority.
+func resolutionSlot(t *testing.T, resolutions string) Slot {
+	var slot Slot
+
+	data := `{"name": "Drink", "value": "coke", "resolutions": {"resolutionsPerAuthority": [` + resolutions + `]}}`
+	if err := json.Unmarshal([]byte(data), &slot); err != nil {
+		t.Fatalf("unable to decode slot: %v", err)
+	}
+
+	return slot
+}
+
+const (
+	staticMatch = `{
+		"authority": "amzn1.er-authority.echo-sdk.amzn1.ask.skill.1.Drink",
+		"status": {"code": "ER_SUCCESS_MATCH"},
+		"values": [
+			{"value": {"name": "Coca-Cola", "id": "COLA"}},
+			{"value": {"name": "Cocoa", "id": "COCOA"}}
+		]
+	}`
+	dynamicMatch = `{
+		"authority": "amzn1.er-authority.echo-sdk.dynamic.amzn1.ask.skill.1.Drink",
+		"status": {"code": "ER_SUCCESS_MATCH"},
+		"values": [{"value": {"name": "Diet Coke", "id": "DIET"}}]
+	}`
+	staticNoMatch = `{
+		"authority": "amzn1.er-authority.echo-sdk.amzn1.ask.skill.1.Drink",
+		"status": {"code": "ER_SUCCESS_NO_MATCH"}
+	}`
+	dynamicNoMatch = `{
+		"authority": "amzn1.er-authority.echo-sdk.dynamic.amzn1.ask.skill.1.Drink",
+		"status": {"code": "ER_SUCCESS_NO_MATCH"}
+	}`
+	staticTimeout = `{
+		"authority": "amzn1.er-authority.echo-sdk.amzn1.ask.skill.1.Drink",
+		"status": {"code": "ER_ERROR_TIMEOUT"}
+	}`
+	dynamicException = `{
+		"authority": "amzn1.er-authority.echo-sdk.dynamic.amzn1.ask.skill.1.Drink",
+		"status": {"code": "ER_ERROR_EXCEPTION"}
+	}`
+)
+
+func TestSlotResolved(t *testing.T) {
+	for _, tt := range []struct {
+		name        string
+		resolutions string
+		id          string
+		dynamic     bool
+		err         error
+	}{
+		{"static", staticMatch, "COLA", false, nil},
+		{"dynamic", dynamicMatch, "DIET", true, nil},
+		{"dynamic first", staticMatch + "," + dynamicMatch, "DIET", true, nil},
+		{"static only match", dynamicNoMatch + "," + staticMatch, "COLA", false, nil},
+		{"no match", staticNoMatch + "," + dynamicNoMatch, "", false, ErrNoMatch},
+		{"timeout", dynamicNoMatch + "," + staticTimeout, "", false, ErrResolutionTimeout},
+		{"exception", staticNoMatch + "," + dynamicException, "", false, ErrResolutionFailed},
+		{"timeout before exception", dynamicException + "," + staticTimeout, "", false, ErrResolutionTimeout},
+		{"match despite timeout", staticTimeout + "," + dynamicMatch, "DIET", true, nil},
+		{"none", "", "", false, ErrNoResolutions},
+	} {
+		slot := resolutionSlot(t, tt.resolutions)
+
+		v, err := slot.Resolved()
+		if err != tt.err {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.err, err)
+			continue
+		}
+
+		if slot.ResolvedID() != tt.id {
+			t.Errorf("%s: expected ID %q, got %q", tt.name, tt.id, slot.ResolvedID())
+		}
+
+		if v != nil && v.Dynamic() != tt.dynamic {
+			t.Errorf("%s: expected dynamic %v, got %v", tt.name, tt.dynamic, v.Dynamic())
+		}
+	}
+}
+
+func TestSlotMatches(t *testing.T) {
+	slot := resolutionSlot(t, staticMatch+","+dynamicMatch)
+
+	want := []ResolvedValue{
+		{ID: "DIET", Name: "Diet Coke", Authority: "amzn1.er-authority.echo-sdk.dynamic.amzn1.ask.skill.1.Drink"},
+		{ID: "COLA", Name: "Coca-Cola", Authority: "amzn1.er-authority.echo-sdk.amzn1.ask.skill.1.Drink"},
+		{ID: "COCOA", Name: "Cocoa", Authority: "amzn1.er-authority.echo-sdk.amzn1.ask.skill.1.Drink"},
+	}
+
+	if got := slot.Matches(); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+}
@@ -0,0 +1,45 @@
+package parser
+
+// Values gets every value of the slot, whether it has a single value or
+// multiple. Each one is a Simple SlotValue with its own Resolutions. A slot
+// that was not filled has none.
+func (s Slot) Values() []SlotValue {
+	if s.SlotValue != nil {
+		if s.SlotValue.Type == SlotValueList {
+			return s.SlotValue.Values
+		}
+
+		return []SlotValue{*s.SlotValue}
+	}
+
+	// Requests without slotValue only have a single value
+	if s.Value == "" {
+		return nil
+	}
+
+	return []SlotValue{{
+		Type:        SlotValueSimple,
+		Value:       s.Value,
+		Resolutions: s.Resolutions,
+	}}
+}
+
+// Strings gets the text of every value of the slot.
+func (s Slot) Strings() []string {
+	values := s.Values()
+	if len(values) == 0 {
+		return nil
+	}
+
+	strs := make([]string, len(values))
+	for i, v := range values {
+		strs[i] = v.Value
+	}
+
+	return strs
+}
+
+// IsList is if the slot may have multiple values.
+func (s Slot) IsList() bool {
+	return s.SlotValue != nil && s.SlotValue.Type == SlotValueList
+}
@@ -0,0 +1,81 @@
+package parser
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSlotValues(t *testing.T) {
+	sample, err := ioutil.ReadFile(filepath.Join("testdata", "multi_value_slot.json"))
+	if err != nil {
+		t.Fatalf("unable to read sample: %v", err)
+	}
+
+	ev, err := Parse(sample)
+	if err != nil {
+		t.Fatalf("unable to parse: %v", err)
+	}
+
+	items := ev.Request.Intent.Slots["Items"]
+	if !items.IsList() {
+		t.Error("expected Items to be a list")
+	}
+
+	if got, want := items.Strings(), []string{"milk", "eggs"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	values := items.Values()
+	if len(values) != 2 {
+		t.Fatalf("expected 2 values, got %d", len(values))
+	}
+
+	if id := values[0].ResolvedID(); id != "MILK" {
+		t.Errorf("expected milk to resolve to MILK, got %q", id)
+	}
+
+	if _, err = values[1].Resolved(); err != ErrNoMatch {
+		t.Errorf("expected eggs not to match, got %v", err)
+	}
+
+	// The slot itself resolves to its first value
+	if id := items.ResolvedID(); id != "MILK" {
+		t.Errorf("expected the slot to resolve to MILK, got %q", id)
+	}
+
+	resolved, err := items.ResolvedValues()
+	if err != ErrNoMatch {
+		t.Errorf("expected eggs not matching to be reported, got %v", err)
+	}
+	if len(resolved) != 2 || resolved[0] == nil || resolved[0].ID != "MILK" || resolved[1] != nil {
+		t.Errorf("expected only milk to resolve, got %+v", resolved)
+	}
+
+	if got, want := items.ResolvedIDs(), []string{"MILK", ""}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected IDs %q, got %q", want, got)
+	}
+
+	store := ev.Request.Intent.Slots["Store"]
+	if store.IsList() {
+		t.Error("expected Store not to be a list")
+	}
+
+	if got, want := store.Strings(), []string{"corner shop"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestSlotValuesWithoutSlotValue(t *testing.T) {
+	slot := Slot{Name: "Drink", Value: "coke"}
+
+	values := slot.Values()
+	if len(values) != 1 || values[0].Type != SlotValueSimple || values[0].Value != "coke" {
+		t.Errorf("expected a simple value, got %+v", values)
+	}
+
+	if values := (Slot{Name: "Drink"}).Values(); values != nil {
+		t.Errorf("expected no values for an empty slot, got %+v", values)
+	}
+}
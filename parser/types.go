@@ -1,6 +1,7 @@
 package parser
 
 import (
+	"encoding/json"
 	"time"
 )
 
@@ -12,6 +13,27 @@ type Event struct {
 	Request Request `json:"request"`
 }
 
+// ApplicationID gets the ID of the application the event is for. It uses the
+// session when there is one, otherwise the context, as requests such as
+// AudioPlayer events do not include a session.
+func (e *Event) ApplicationID() string {
+	if e.Session.Application.ID != "" {
+		return e.Session.Application.ID
+	}
+
+	return e.Context.System.Application.ID
+}
+
+// UserID gets the ID of the user the event is from. It uses the session when
+// there is one, otherwise the context.
+func (e *Event) UserID() string {
+	if e.Session.User.ID != "" {
+		return e.Session.User.ID
+	}
+
+	return e.Context.System.User.ID
+}
+
 // Session is information about the user, any set session data, or the app.
 type Session struct {
 	ID          string            `json:"sessionId"`
@@ -33,7 +55,14 @@ type Application struct {
 // Permissions hold the consent token which can retrieve additional information regarding
 // the current user
 type Permissions struct {
-	ConsentToken string `json:"consentToken,omitempty"`
+	ConsentToken string           `json:"consentToken,omitempty"`
+	Scopes       map[string]Scope `json:"scopes,omitempty"`
+}
+
+// Scope is if the user has granted a permission scope, such as
+// "alexa::devices:all:geolocation:read".
+type Scope struct {
+	Status string `json:"status"`
 }
 
 // User is information about the user, including access token if one has been
@@ -45,6 +74,8 @@ type User struct {
 }
 
 // Request is information about the request, including the intent and data.
+// Fields specific to the type of request are in Body, which is nil for types
+// without one.
 type Request struct {
 	ID        string `json:"requestId"`
 	Type      string `json:"type"`
@@ -52,6 +83,9 @@ type Request struct {
 	Timestamp *Time  `json:"timestamp"`
 	Intent    Intent `json:"intent,omitempty"`
 	Reason    string `json:"reason,omitempty"`
+
+	Body RequestBody     `json:"-"`
+	Raw  json.RawMessage `json:"-"`
 }
 
 // Time is a timestamp of the request.
@@ -59,7 +93,7 @@ type Time time.Time
 
 // MarshalJSON allows for encoding the timestamp in the correct format.
 func (t Time) MarshalJSON() ([]byte, error) {
-	return []byte(time.Time(t).Format(time.RFC3339)), nil
+	return []byte("\"" + time.Time(t).Format(time.RFC3339) + "\""), nil
 }
 
 // UnmarshalJSON allows for decoding the time in the correct format.
@@ -89,9 +123,29 @@ type Intent struct {
 
 // Slot is the data for an intent.
 type Slot struct {
-	Name        string      `json:"name"`
-	Value       string      `json:"value"`
+	Name               string      `json:"name"`
+	Value              string      `json:"value"`
+	Resolutions        Resolutions `json:"resolutions,omitempty"`
+	ConfirmationStatus string      `json:"confirmationStatus,omitempty"`
+	Source             string      `json:"source,omitempty"`
+	SlotValue          *SlotValue  `json:"slotValue,omitempty"`
+}
+
+// Types of SlotValue.
+const (
+	// SlotValueSimple is a single value.
+	SlotValueSimple = "Simple"
+	// SlotValueList is multiple values, such as "milk, eggs and bread".
+	SlotValueList = "List"
+)
+
+// SlotValue is the value of a slot. A Simple value has a Value and
+// Resolutions, and a List has Values which are each Simple.
+type SlotValue struct {
+	Type        string      `json:"type"`
+	Value       string      `json:"value,omitempty"`
 	Resolutions Resolutions `json:"resolutions,omitempty"`
+	Values      []SlotValue `json:"values,omitempty"`
 }
 
 // Resolutions is the data for a resolution contained within a slot that contains an array of resolution
@@ -135,12 +189,36 @@ type ResolutionValueValue struct {
 
 // AudioPlayer holds info about the audioplayer usage of the users device
 type AudioPlayer struct {
-	PlayerActivity string `json:"playerActivity,omitempty"`
+	Token                string `json:"token,omitempty"`
+	OffsetInMilliseconds int64  `json:"offsetInMilliseconds,omitempty"`
+	PlayerActivity       string `json:"playerActivity,omitempty"`
 }
 
-// SupportedInterfaces Holds information regarding supported interfaces (future oriented - unneccessary atm)
+// SupportedInterfaces Holds information regarding supported interfaces. An
+// interface is supported if it is not nil.
 type SupportedInterfaces struct {
-	AudioPlayer AudioPlayer `json:"AudioPlayer,omitempty"`
+	AudioPlayer AudioPlayer       `json:"AudioPlayer,omitempty"`
+	Display     *DisplayInterface `json:"Display,omitempty"`
+	VideoApp    *struct{}         `json:"VideoApp,omitempty"`
+	APL         *APLInterface     `json:"Alexa.Presentation.APL,omitempty"`
+	APLT        *APLInterface     `json:"Alexa.Presentation.APLT,omitempty"`
+	HTML        *struct{}         `json:"Alexa.Presentation.HTML,omitempty"`
+	Geolocation *struct{}         `json:"Geolocation,omitempty"`
+	Navigation  *struct{}         `json:"Navigation,omitempty"`
+}
+
+// DisplayInterface is the versions of the Display interface the device
+// supports.
+type DisplayInterface struct {
+	TemplateVersion string `json:"templateVersion,omitempty"`
+	MarkupVersion   string `json:"markupVersion,omitempty"`
+}
+
+// APLInterface is the version of APL the device supports.
+type APLInterface struct {
+	Runtime struct {
+		MaxVersion string `json:"maxVersion,omitempty"`
+	} `json:"runtime"`
 }
 
 // Device holds information regarding the users device
@@ -149,16 +227,194 @@ type Device struct {
 	Interfaces SupportedInterfaces `json:"supportedInterfaces,omitempty"`
 }
 
+// Person is the person recognized by their voice, who may not be the owner of
+// the account.
+type Person struct {
+	ID          string `json:"personId"`
+	AccessToken string `json:"accessToken,omitempty"`
+}
+
+// Unit is the organizational unit, such as a hotel room, the device is in.
+type Unit struct {
+	ID           string `json:"unitId"`
+	PersistentID string `json:"persistentUnitId,omitempty"`
+}
+
 // System holds information regarding the users system (dot, alexa ... future stuff)
 type System struct {
-	Application Application `json:"application,omitempty"`
-	User        User        `json:"user,omitempty"`
-	Device      Device      `json:"device,omitempty"`
-	APIEndpoint string      `json:"apiEndpoint,omitempty"`
+	Application    Application `json:"application,omitempty"`
+	User           User        `json:"user,omitempty"`
+	Person         *Person     `json:"person,omitempty"`
+	Unit           *Unit       `json:"unit,omitempty"`
+	Device         Device      `json:"device,omitempty"`
+	APIEndpoint    string      `json:"apiEndpoint,omitempty"`
+	APIAccessToken string      `json:"apiAccessToken,omitempty"`
+}
+
+// Display holds the token of the template shown on the device's screen.
+type Display struct {
+	Token string `json:"token,omitempty"`
+}
+
+// Viewport is information about the device's screen.
+type Viewport struct {
+	Experiences        []ViewportExperience `json:"experiences,omitempty"`
+	Mode               string               `json:"mode,omitempty"`
+	Shape              string               `json:"shape,omitempty"`
+	PixelWidth         int                  `json:"pixelWidth,omitempty"`
+	PixelHeight        int                  `json:"pixelHeight,omitempty"`
+	DPI                int                  `json:"dpi,omitempty"`
+	CurrentPixelWidth  int                  `json:"currentPixelWidth,omitempty"`
+	CurrentPixelHeight int                  `json:"currentPixelHeight,omitempty"`
+	Touch              []string             `json:"touch,omitempty"`
+	Keyboard           []string             `json:"keyboard,omitempty"`
+	Video              *ViewportVideo       `json:"video,omitempty"`
+}
+
+// ViewportExperience is a way the screen may be viewed.
+type ViewportExperience struct {
+	ArcMinuteWidth  int  `json:"arcMinuteWidth,omitempty"`
+	ArcMinuteHeight int  `json:"arcMinuteHeight,omitempty"`
+	CanRotate       bool `json:"canRotate"`
+	CanResize       bool `json:"canResize"`
+}
+
+// ViewportVideo is the video codecs a screen supports.
+type ViewportVideo struct {
+	Codecs []string `json:"codecs,omitempty"`
+}
+
+// ViewportDescriptor is one of the displays on the device. Its Type is "APL"
+// for screens, or "APLT" for character displays.
+type ViewportDescriptor struct {
+	Type string `json:"type"`
+	ID   string `json:"id,omitempty"`
+
+	// APL screens
+	Shape            string                 `json:"shape,omitempty"`
+	DPI              int                    `json:"dpi,omitempty"`
+	PresentationType string                 `json:"presentationType,omitempty"`
+	CanRotate        bool                   `json:"canRotate,omitempty"`
+	Configuration    *ViewportConfiguration `json:"configuration,omitempty"`
+
+	// APLT character displays
+	SupportedProfiles []string       `json:"supportedProfiles,omitempty"`
+	LineLength        int            `json:"lineLength,omitempty"`
+	LineCount         int            `json:"lineCount,omitempty"`
+	Format            string         `json:"format,omitempty"`
+	InterSegments     []InterSegment `json:"interSegments,omitempty"`
+}
+
+// ViewportConfiguration is the current configuration of an APL screen.
+type ViewportConfiguration struct {
+	Current struct {
+		Mode  string         `json:"mode,omitempty"`
+		Video *ViewportVideo `json:"video,omitempty"`
+		Size  ViewportSize   `json:"size"`
+	} `json:"current"`
+}
+
+// ViewportSize is the size of an APL screen. DISCRETE sizes have a width and
+// height, and CONTINUOUS sizes have a range of them.
+type ViewportSize struct {
+	Type           string `json:"type"`
+	PixelWidth     int    `json:"pixelWidth,omitempty"`
+	PixelHeight    int    `json:"pixelHeight,omitempty"`
+	MinPixelWidth  int    `json:"minPixelWidth,omitempty"`
+	MinPixelHeight int    `json:"minPixelHeight,omitempty"`
+	MaxPixelWidth  int    `json:"maxPixelWidth,omitempty"`
+	MaxPixelHeight int    `json:"maxPixelHeight,omitempty"`
+}
+
+// InterSegment is a character between the segments of an APLT display, such
+// as the colon of a clock.
+type InterSegment struct {
+	X          int    `json:"x"`
+	Y          int    `json:"y"`
+	Characters string `json:"characters"`
+}
+
+// Geolocation is the location of the device, if the user allowed it.
+type Geolocation struct {
+	LocationServices *LocationServices `json:"locationServices,omitempty"`
+	Timestamp        *Time             `json:"timestamp,omitempty"`
+	Coordinate       *Coordinate       `json:"coordinate,omitempty"`
+	Altitude         *Altitude         `json:"altitude,omitempty"`
+	Heading          *Heading          `json:"heading,omitempty"`
+	Speed            *Speed            `json:"speed,omitempty"`
+}
+
+// LocationServices is if location sharing is enabled on the device.
+type LocationServices struct {
+	Access string `json:"access"`
+	Status string `json:"status"`
+}
+
+// Coordinate is the latitude and longitude of the device.
+type Coordinate struct {
+	LatitudeInDegrees  float64 `json:"latitudeInDegrees"`
+	LongitudeInDegrees float64 `json:"longitudeInDegrees"`
+	AccuracyInMeters   float64 `json:"accuracyInMeters"`
+}
+
+// Altitude is the altitude of the device.
+type Altitude struct {
+	AltitudeInMeters float64 `json:"altitudeInMeters"`
+	AccuracyInMeters float64 `json:"accuracyInMeters"`
+}
+
+// Heading is the direction the device is going.
+type Heading struct {
+	DirectionInDegrees float64 `json:"directionInDegrees"`
+	AccuracyInDegrees  float64 `json:"accuracyInDegrees,omitempty"`
+}
+
+// Speed is how fast the device is going.
+type Speed struct {
+	SpeedInMetersPerSecond    float64 `json:"speedInMetersPerSecond"`
+	AccuracyInMetresPerSecond float64 `json:"accuracyInMetresPerSecond,omitempty"`
+}
+
+// Extensions are the APL extensions available on the device, by URI.
+type Extensions struct {
+	Available map[string]json.RawMessage `json:"available,omitempty"`
+}
+
+// APLContext is the APL document shown on the device's screen.
+type APLContext struct {
+	Token                     string         `json:"token,omitempty"`
+	Version                   string         `json:"version,omitempty"`
+	ComponentsVisibleOnScreen []APLComponent `json:"componentsVisibleOnScreen,omitempty"`
+}
+
+// APLComponent is a component of an APL document visible on the screen. Tags
+// are keyed by name, such as "focused" or "scrollable", and vary by component.
+type APLComponent struct {
+	UID      string                     `json:"uid"`
+	Position string                     `json:"position,omitempty"`
+	Type     string                     `json:"type,omitempty"`
+	ID       string                     `json:"id,omitempty"`
+	Tags     map[string]json.RawMessage `json:"tags,omitempty"`
+	Children []APLComponent             `json:"children,omitempty"`
+	Entities []json.RawMessage          `json:"entities,omitempty"`
+}
+
+// Advertising is the user's advertising ID.
+type Advertising struct {
+	AdvertisingID   string `json:"advertisingId"`
+	LimitAdTracking bool   `json:"limitAdTracking"`
 }
 
-// Context  holds more context to the users setup
+// Context  holds more context to the users setup. Anything that is not
+// available for the device or request is nil.
 type Context struct {
-	AudioPlayer AudioPlayer `json:"AudioPlayer,omitempty"`
-	System      System      `json:"System,omitempty"`
+	AudioPlayer AudioPlayer          `json:"AudioPlayer,omitempty"`
+	System      System               `json:"System,omitempty"`
+	Display     *Display             `json:"Display,omitempty"`
+	Viewport    *Viewport            `json:"Viewport,omitempty"`
+	Viewports   []ViewportDescriptor `json:"Viewports,omitempty"`
+	Geolocation *Geolocation         `json:"Geolocation,omitempty"`
+	Extensions  *Extensions          `json:"Extensions,omitempty"`
+	APL         *APLContext          `json:"Alexa.Presentation.APL,omitempty"`
+	Advertising *Advertising         `json:"Advertising,omitempty"`
 }
@@ -32,6 +32,10 @@ type Response struct {
 	Version    string                   `json:"version"`
 	Attributes parser.SessionAttributes `json:"sessionAttributes"`
 	Response   InnerResponse            `json:"response"`
+
+	// NextState is the state the conversation moves to, if set. It is stored
+	// in the session attributes by events.StateHandler.
+	NextState *string `json:"-"`
 }
 
 // InnerResponse is all the actual information for the response.
@@ -212,6 +216,14 @@ func (r *Response) SetAttributes(attrs parser.SessionAttributes) *Response {
 	return r
 }
 
+// Transition moves the conversation to a new state once the response is sent.
+// An empty state moves it back to the initial state.
+func (r *Response) Transition(state string) *Response {
+	r.NextState = &state
+
+	return r
+}
+
 // KeepAlive keeps a session alive instead of ending it.
 func (r *Response) KeepAlive() *Response {
 	r.Response.ShouldEndSession = false
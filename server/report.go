@@ -0,0 +1,194 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"runtime/debug"
+	"sync"
+
+	"github.com/go-alexa/alexa/events"
+	"github.com/go-alexa/alexa/parser"
+	"github.com/go-alexa/alexa/response"
+	"github.com/go-alexa/alexa/validations"
+)
+
+// ErrorReport is information about a request whose handler returned an error
+// or panicked.
+type ErrorReport struct {
+	// RequestID is the ID of the request.
+	RequestID string
+	// RequestType is the type of the request.
+	RequestType string
+	// Intent is the name of the intent, if it was an intent request.
+	Intent string
+	// Err is the error returned, or a *events.PanicError for a panic.
+	Err error
+	// Stack is the stack trace of a panic. It is nil for errors.
+	Stack []byte
+}
+
+// Panicked is if the handler panicked instead of returning an error.
+func (r *ErrorReport) Panicked() bool {
+	var perr *events.PanicError
+	return errors.As(r.Err, &perr)
+}
+
+// ErrorReporter is a func called with every handler error or panic.
+type ErrorReporter func(*ErrorReport)
+
+// WithErrorReporter sets the func called with every handler error or panic.
+// By default, they are written to the logger.
+func WithErrorReporter(reporter ErrorReporter) Option {
+	return func(s *Server) {
+		s.reporter = reporter
+	}
+}
+
+// WithPanicResponse sets the response sent when a handler panics. By default,
+// it speaks events.ErrorSpeech.
+func WithPanicResponse(resp *response.Response) Option {
+	return func(s *Server) {
+		s.panicResponse = resp
+	}
+}
+
+// report sends an error from processing an event to the ErrorReporter.
+func (s *Server) report(ev *parser.Event, err error) {
+	report := &ErrorReport{
+		RequestID:   ev.Request.ID,
+		RequestType: ev.Request.Type,
+		Intent:      ev.Request.Intent.Name,
+		Err:         err,
+	}
+
+	var perr *events.PanicError
+	if errors.As(err, &perr) {
+		report.Stack = perr.Stack
+	}
+
+	if s.reporter != nil {
+		s.reporter(report)
+	} else {
+		s.logReport(report)
+	}
+}
+
+// logReport writes an ErrorReport to the logger.
+func (s *Server) logReport(report *ErrorReport) {
+	logf := log.Printf
+	if s.logger != nil {
+		logf = s.logger.Printf
+	}
+
+	if report.Panicked() {
+		logf("Request %s (%s %s) panicked: %v\n%s", report.RequestID,
+			report.RequestType, report.Intent, report.Err, report.Stack)
+	} else {
+		logf("Request %s (%s %s) failed: %v", report.RequestID,
+			report.RequestType, report.Intent, report.Err)
+	}
+}
+
+// Rejection is information about a request that was rejected because it
+// failed validation.
+type Rejection struct {
+	// RemoteAddr is the address the request came from.
+	RemoteAddr string
+	// RequestID is the ID of the request, if it was parsed.
+	RequestID string
+	// Stage is the part of validation that failed. It is empty if a custom
+	// validator did not return a *validations.ValidationError.
+	Stage validations.Stage
+	// Err is why the request was rejected.
+	Err error
+}
+
+// RejectionReporter is a func called with every rejected request.
+type RejectionReporter func(*Rejection)
+
+// WithRejectionReporter sets the func called with every request rejected
+// because it failed validation, such as to log or count them by Stage. By
+// default, rejections are not reported.
+func WithRejectionReporter(reporter RejectionReporter) Option {
+	return func(s *Server) {
+		s.rejecter = reporter
+	}
+}
+
+// reject reports a request that failed validation and writes a
+// http.StatusBadRequest for it. ev is nil if the request was not parsed.
+func (s *Server) reject(w http.ResponseWriter, r *http.Request, ev *parser.Event, err error) {
+	if s.rejecter != nil {
+		rejection := &Rejection{
+			RemoteAddr: r.RemoteAddr,
+			Err:        err,
+		}
+
+		if ev != nil {
+			rejection.RequestID = ev.Request.ID
+		}
+
+		var verr *validations.ValidationError
+		if errors.As(err, &verr) {
+			rejection.Stage = verr.Stage
+		}
+
+		s.rejecter(rejection)
+	}
+
+	writeBadRequest(w)
+}
+
+// bodyError creates a *validations.ValidationError for a body that could not
+// be parsed.
+func bodyError(err error) error {
+	return &validations.ValidationError{
+		Stage: validations.StageBody,
+		Err:   err,
+	}
+}
+
+// handledErrors are the errors an event handler reported for a request, so
+// they are not reported again if it also returns them.
+type handledErrors struct {
+	mu   sync.Mutex
+	errs []error
+}
+
+// add records a reported error.
+func (h *handledErrors) add(err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.errs = append(h.errs, err)
+}
+
+// has is if an error was already reported.
+func (h *handledErrors) has(err error) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, reported := range h.errs {
+		if errors.Is(err, reported) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// process processes an event, turning any panic into a *events.PanicError.
+func process(ctx context.Context, h events.EventHandler, ev *parser.Event) (resp *response.Response, err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			resp, err = nil, &events.PanicError{
+				Value: v,
+				Stack: debug.Stack(),
+			}
+		}
+	}()
+
+	return events.Process(ctx, h, ev)
+}
@@ -2,8 +2,18 @@
 package server
 
 import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"log"
 	"net/http"
 	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
 	"encoding/json"
 
@@ -11,6 +21,7 @@ import (
 
 	"github.com/go-alexa/alexa/events"
 	"github.com/go-alexa/alexa/parser"
+	"github.com/go-alexa/alexa/response"
 	"github.com/go-alexa/alexa/validations"
 )
 
@@ -21,6 +32,610 @@ var Host = os.Getenv("HTTP_HOST")
 // Events is the event handler.
 var Events events.EventHandler
 
+// DefaultPath is the path the Alexa handler is mounted on.
+const DefaultPath = "/alexa"
+
+// DefaultShutdownTimeout is how long RunContext waits for in-flight requests
+// to finish before giving up.
+const DefaultShutdownTimeout = 10 * time.Second
+
+// DefaultDeadline is how long a Server gives handlers to respond. Alexa gives
+// up on a request after about 8 seconds.
+const DefaultDeadline = 7 * time.Second
+
+// DefaultTimeoutSpeech is spoken when a handler does not respond before the
+// deadline.
+const DefaultTimeoutSpeech = "Sorry, that took too long. Please try again."
+
+// shutdownPollInterval is how often Shutdown checks for in-flight requests.
+const shutdownPollInterval = 50 * time.Millisecond
+
+// ShutdownError is returned when requests were still running when the
+// deadline for a shutdown was reached.
+type ShutdownError struct {
+	// Err is the reason the shutdown did not finish, usually the context error.
+	Err error
+	// InFlight describes each request that was still running.
+	InFlight []string
+}
+
+func (e *ShutdownError) Error() string {
+	return fmt.Sprintf("server: %v with %d requests still running: %s",
+		e.Err, len(e.InFlight), strings.Join(e.InFlight, ", "))
+}
+
+// Unwrap returns the reason the shutdown did not finish.
+func (e *ShutdownError) Unwrap() error {
+	return e.Err
+}
+
+// validator is anything that is able to validate an incoming request.
+type validator interface {
+	requestValidator
+
+	ValidateCertificate(*http.Request) (*x509.Certificate, error)
+	ValidateSignatureAlgorithm(*http.Request, *x509.Certificate) ([]byte, x509.SignatureAlgorithm, error)
+}
+
+// requestValidator is anything that is able to validate a parsed request.
+type requestValidator interface {
+	ValidateRequest(*parser.Event) error
+}
+
+// globalValidator validates requests with the package level functions of
+// validations, so it uses validations.DB, validations.AppID and
+// validations.TimeLimit.
+type globalValidator struct{}
+
+func (globalValidator) ValidateCertificate(r *http.Request) (*x509.Certificate, error) {
+	return validations.ValidateCertificate(r)
+}
+
+func (globalValidator) ValidateSignatureAlgorithm(r *http.Request, cert *x509.Certificate) ([]byte, x509.SignatureAlgorithm, error) {
+	return validations.ValidateSignatureAlgorithm(r, cert)
+}
+
+func (globalValidator) ValidateRequest(ev *parser.Event) error {
+	return validations.ValidateRequest(ev)
+}
+
+// ErrUnknownApp means a request was for an application with no Skill added.
+// It is the Err of the *validations.ValidationError the request is rejected
+// with, which has the application ID as its Detail.
+var ErrUnknownApp = errors.New("no skill was added for this application ID")
+
+// Skill is the configuration for one of many Skills hosted by a Server.
+type Skill struct {
+	// AppID is the Skill's ID. Requests are routed to the Skill by it.
+	AppID string
+	// Events is the event handler for the Skill.
+	Events events.EventHandler
+	// TimeLimit is the maximum variance allowed in the timestamp, in seconds.
+	// If zero, the Server's time limit is used.
+	TimeLimit float64
+	// DefaultLocale is set as the request's locale when it does not have one.
+	DefaultLocale string
+}
+
+// route is where a request is sent after its certificate and signature have
+// been validated.
+type route struct {
+	events    events.EventHandler
+	validator requestValidator
+	locale    string
+}
+
+// Server is a server for Alexa Skills. Everything it needs is owned by
+// the Server, so multiple Servers may run in the same process.
+type Server struct {
+	// Addr is the address for the HTTP server to listen on.
+	Addr string
+
+	events    events.EventHandler
+	validator validator
+	skills    []Skill
+	routes    map[string]*route
+	appID     string
+	timeLimit float64
+	certCache validations.CertCache
+	client    *http.Client
+	roots     *x509.CertPool
+	allowSHA1 bool
+	signed    SignatureReporter
+	replay    validations.ReplayStore
+	logger    *log.Logger
+	path      string
+	mux       *http.ServeMux
+
+	deadline        time.Duration
+	timeoutResponse *response.Response
+	panicResponse   *response.Response
+	reporter        ErrorReporter
+	rejecter        RejectionReporter
+	shutdownTimeout time.Duration
+	// closers were created by the Server, so Shutdown closes them.
+	closers []io.Closer
+
+	mu         sync.Mutex
+	httpServer *http.Server
+	nextID     uint64
+	inFlight   map[uint64]*flight
+}
+
+// Option configures a Server.
+type Option func(*Server)
+
+// WithAddr sets the address for the HTTP server to listen on.
+func WithAddr(addr string) Option {
+	return func(s *Server) {
+		s.Addr = addr
+	}
+}
+
+// WithEvents sets the event handler for the Server.
+func WithEvents(ev events.EventHandler) Option {
+	return func(s *Server) {
+		s.events = ev
+	}
+}
+
+// WithSkill adds a Skill to the Server. Once any Skill is added, requests are
+// routed by their application ID and requests for unknown IDs are rejected.
+// WithEvents and WithAppID are then ignored.
+func WithSkill(skill Skill) Option {
+	return func(s *Server) {
+		s.skills = append(s.skills, skill)
+	}
+}
+
+// WithValidator sets the Validator used to validate requests. When set,
+// WithAppID, WithTimeLimit and the WithCert options are ignored.
+func WithValidator(v *validations.Validator) Option {
+	return func(s *Server) {
+		if v != nil {
+			s.validator = v
+		}
+	}
+}
+
+// WithAppID sets the Skill's ID. Requests for any other ID are rejected.
+func WithAppID(appID string) Option {
+	return func(s *Server) {
+		s.appID = appID
+	}
+}
+
+// WithTimeLimit sets the maximum variance allowed in the timestamp from the
+// current time, in seconds.
+func WithTimeLimit(seconds float64) Option {
+	return func(s *Server) {
+		s.timeLimit = seconds
+	}
+}
+
+// WithCertCache sets the cache for certificate chains. By default, they are
+// cached in memory.
+func WithCertCache(cache validations.CertCache) Option {
+	return func(s *Server) {
+		s.certCache = cache
+	}
+}
+
+// WithCertClient sets the client used to download certificate chains.
+func WithCertClient(client *http.Client) Option {
+	return func(s *Server) {
+		s.client = client
+	}
+}
+
+// WithCertRoots sets the roots signing certificates must chain to. By default,
+// the system's roots are used.
+func WithCertRoots(roots *x509.CertPool) Option {
+	return func(s *Server) {
+		s.roots = roots
+	}
+}
+
+// WithReplayStore sets the store used to reject requests that were already
+// handled. By default, replayed requests are only rejected once they are
+// outside the time limit.
+func WithReplayStore(store validations.ReplayStore) Option {
+	return func(s *Server) {
+		s.replay = store
+	}
+}
+
+// WithSHA1 allows requests with only the legacy SHA-1 signature. By default,
+// requests must have a SHA-256 signature.
+func WithSHA1(allow bool) Option {
+	return func(s *Server) {
+		s.allowSHA1 = allow
+	}
+}
+
+// SignatureReporter is a func called with the algorithm that verified the
+// signature of a request.
+type SignatureReporter func(*http.Request, x509.SignatureAlgorithm)
+
+// WithSignatureReporter sets the func called with the algorithm that verified
+// the signature of every request, such as to monitor how many still use
+// SHA-1.
+func WithSignatureReporter(reporter SignatureReporter) Option {
+	return func(s *Server) {
+		s.signed = reporter
+	}
+}
+
+// WithLogger sets the logger used for requests and warnings.
+func WithLogger(l *log.Logger) Option {
+	return func(s *Server) {
+		s.logger = l
+	}
+}
+
+// WithDeadline sets how long handlers are given to respond. Their context is
+// done once it passes and the timeout response is sent instead. A deadline of
+// zero means there is none. By default, it is DefaultDeadline.
+func WithDeadline(d time.Duration) Option {
+	return func(s *Server) {
+		s.deadline = d
+	}
+}
+
+// WithTimeoutResponse sets the response sent when a handler does not respond
+// before the deadline. By default, it speaks DefaultTimeoutSpeech.
+func WithTimeoutResponse(resp *response.Response) Option {
+	return func(s *Server) {
+		s.timeoutResponse = resp
+	}
+}
+
+// WithShutdownTimeout sets how long RunContext waits for in-flight requests to
+// finish once its context is done. By default, it is DefaultShutdownTimeout.
+func WithShutdownTimeout(d time.Duration) Option {
+	return func(s *Server) {
+		s.shutdownTimeout = d
+	}
+}
+
+// WithPath sets the path the Alexa handler is mounted on. By default, it is
+// DefaultPath.
+func WithPath(path string) Option {
+	return func(s *Server) {
+		s.path = path
+	}
+}
+
+var (
+	// globalMu protects global.
+	globalMu sync.Mutex
+	// global is the Server last created with withGlobals.
+	global *Server
+	// mountGlobal mounts the handler of global on http.DefaultServeMux.
+	mountGlobal sync.Once
+)
+
+// setGlobal makes s handle requests to DefaultPath of http.DefaultServeMux.
+// The handler is only mounted once, as http.DefaultServeMux does not allow it
+// to be replaced.
+func setGlobal(s *Server) {
+	globalMu.Lock()
+	global = s
+	globalMu.Unlock()
+
+	mountGlobal.Do(func() {
+		http.HandleFunc(DefaultPath, func(w http.ResponseWriter, r *http.Request) {
+			globalMu.Lock()
+			g := global
+			globalMu.Unlock()
+
+			g.handle(w, r)
+		})
+	})
+}
+
+// withGlobals makes the Server use the package level globals of server and
+// validations, as Run always has. The handler is mounted on
+// http.DefaultServeMux, so any other handlers registered on it are served too.
+func withGlobals() Option {
+	return func(s *Server) {
+		s.Addr = Host
+		s.mux = http.DefaultServeMux
+		setGlobal(s)
+		s.events = Events
+		s.validator = globalValidator{}
+		if validations.DB != nil {
+			s.closers = append(s.closers, validations.DB)
+		}
+	}
+}
+
+// New creates a new Server with the options applied.
+func New(opts ...Option) *Server {
+	s := &Server{
+		timeLimit: 60,
+		logger:    log.New(os.Stdout, "", log.LstdFlags),
+		path:      DefaultPath,
+		mux:       http.NewServeMux(),
+
+		deadline:        DefaultDeadline,
+		timeoutResponse: response.New().AddSpeech(DefaultTimeoutSpeech),
+		panicResponse:   response.New().AddSpeech(events.ErrorSpeech),
+		shutdownTimeout: DefaultShutdownTimeout,
+		inFlight:        make(map[uint64]*flight),
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	if s.validator == nil {
+		if s.certCache == nil {
+			s.certCache = validations.NewMemoryCertCache()
+		}
+
+		s.validator = &validations.Validator{
+			AppID:     s.appID,
+			TimeLimit: s.timeLimit,
+			Cache:     s.certCache,
+			Client:    s.client,
+			Roots:     s.roots,
+			AllowSHA1: s.allowSHA1,
+			Replay:    s.replay,
+
+			RefreshBefore: validations.DefaultRefreshBefore,
+		}
+	}
+
+	if len(s.skills) > 0 {
+		s.routes = make(map[string]*route, len(s.skills))
+
+		// Skills share the replay store and clock of the Validator, which
+		// may have been set by WithValidator
+		replay := s.replay
+		var clock func() time.Time
+		if v, ok := s.validator.(*validations.Validator); ok {
+			if v.Replay != nil {
+				replay = v.Replay
+			}
+			clock = v.Clock
+		}
+
+		for _, skill := range s.skills {
+			timeLimit := skill.TimeLimit
+			if timeLimit == 0 {
+				timeLimit = s.timeLimit
+			}
+
+			s.routes[skill.AppID] = &route{
+				events: skill.Events,
+				validator: &validations.Validator{
+					AppID:     skill.AppID,
+					TimeLimit: timeLimit,
+					Replay:    replay,
+					Clock:     clock,
+				},
+				locale: skill.DefaultLocale,
+			}
+		}
+	}
+
+	// withGlobals has already mounted it on http.DefaultServeMux
+	if s.mux != http.DefaultServeMux {
+		s.mux.HandleFunc(s.path, s.handle)
+	}
+
+	return s
+}
+
+// route finds where an event should be sent.
+func (s *Server) route(ev *parser.Event) (*route, error) {
+	if s.routes == nil {
+		return &route{
+			events:    s.events,
+			validator: s.validator,
+		}, nil
+	}
+
+	appID := ev.ApplicationID()
+
+	rt, ok := s.routes[appID]
+	if !ok {
+		return nil, &validations.ValidationError{
+			Stage:  validations.StageApplication,
+			Err:    ErrUnknownApp,
+			Detail: appID,
+		}
+	}
+
+	return rt, nil
+}
+
+// ServeHTTP allows the Server to be used as an http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// server gets the underlying http.Server, creating it if needed.
+func (s *Server) server() *http.Server {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.httpServer == nil {
+		s.httpServer = &http.Server{
+			Addr: s.Addr,
+			Handler: handlers.LoggingHandler(s.logger.Writer(),
+				handlers.ProxyHeaders(s)),
+		}
+	}
+
+	return s.httpServer
+}
+
+// ListenAndServe listens on Addr and serves requests with request logging.
+// After Shutdown, it returns http.ErrServerClosed.
+func (s *Server) ListenAndServe() error {
+	return s.server().ListenAndServe()
+}
+
+// RunContext listens on Addr until ctx is done, then shuts down, waiting up to
+// the shutdown timeout for in-flight requests to finish.
+func (s *Server) RunContext(ctx context.Context) error {
+	errc := make(chan error, 1)
+	go func() {
+		errc <- s.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.shutdownTimeout)
+	defer cancel()
+
+	err := s.Shutdown(shutdownCtx)
+
+	if lerr := <-errc; lerr != http.ErrServerClosed && err == nil {
+		err = lerr
+	}
+
+	return err
+}
+
+// Shutdown stops accepting connections and waits for in-flight requests to
+// finish, including any served through ServeHTTP. It then closes anything the
+// Server created itself, such as validations.DB for RunContext. Stores given
+// with WithCertCache or WithReplayStore are left for the caller to close. If
+// ctx is done first, a *ShutdownError describing the requests still running
+// is returned, and nothing is closed as they may still be using it.
+func (s *Server) Shutdown(ctx context.Context) error {
+	err := s.server().Shutdown(ctx)
+	if err == nil {
+		err = s.wait(ctx)
+	}
+
+	if err != nil {
+		if running := s.InFlight(); len(running) > 0 {
+			return &ShutdownError{
+				Err:      err,
+				InFlight: running,
+			}
+		}
+	}
+
+	s.mu.Lock()
+	closers := s.closers
+	s.closers = nil
+	s.mu.Unlock()
+
+	for _, c := range closers {
+		if cerr := c.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+
+	return err
+}
+
+// wait waits until there are no in-flight requests or ctx is done.
+func (s *Server) wait(ctx context.Context) error {
+	ticker := time.NewTicker(shutdownPollInterval)
+	defer ticker.Stop()
+
+	for {
+		if len(s.InFlight()) == 0 {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// InFlight describes each request currently being handled.
+func (s *Server) InFlight() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	running := make([]string, 0, len(s.inFlight))
+	for _, f := range s.inFlight {
+		running = append(running, f.desc)
+	}
+	sort.Strings(running)
+
+	return running
+}
+
+// flight is an in-flight request. It is removed once refs reaches zero.
+type flight struct {
+	desc string
+	refs int
+}
+
+// track records a request as in-flight until untrack is called.
+func (s *Server) track(desc string) uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.inFlight == nil {
+		s.inFlight = make(map[uint64]*flight)
+	}
+
+	s.nextID++
+	s.inFlight[s.nextID] = &flight{desc: desc, refs: 1}
+
+	return s.nextID
+}
+
+// describe updates the description of an in-flight request.
+func (s *Server) describe(id uint64, desc string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if f, ok := s.inFlight[id]; ok {
+		f.desc = desc
+	}
+}
+
+// retain keeps a request in-flight until untrack is called once more, such as
+// for a handler still running after the response was sent.
+func (s *Server) retain(id uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if f, ok := s.inFlight[id]; ok {
+		f.refs++
+	}
+}
+
+// untrack removes a request from the in-flight requests once everything
+// handling it has finished.
+func (s *Server) untrack(id uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if f, ok := s.inFlight[id]; ok {
+		if f.refs--; f.refs <= 0 {
+			delete(s.inFlight, id)
+		}
+	}
+}
+
+// describeEvent describes an event for the in-flight requests.
+func describeEvent(ev *parser.Event) string {
+	desc := ev.Request.ID + " " + ev.Request.Type
+	if ev.Request.Intent.Name != "" {
+		desc += " " + ev.Request.Intent.Name
+	}
+
+	return desc
+}
+
 // writeBadRequest writes a http.StatusBadRequest error and message
 func writeBadRequest(w http.ResponseWriter) {
 	w.WriteHeader(http.StatusBadRequest)
@@ -33,47 +648,104 @@ func writeServerError(w http.ResponseWriter) {
 	w.Write([]byte(http.StatusText(http.StatusInternalServerError)))
 }
 
-// Handler is the function that handles the Alexa HTTP request.
+// Handler is the function that handles the Alexa HTTP request. It uses Events
+// and the package level validations, and responds within DefaultDeadline.
 func Handler(w http.ResponseWriter, r *http.Request) {
+	s := Server{
+		events:    Events,
+		validator: globalValidator{},
+
+		deadline:        DefaultDeadline,
+		timeoutResponse: response.New().AddSpeech(DefaultTimeoutSpeech),
+		panicResponse:   response.New().AddSpeech(events.ErrorSpeech),
+	}
+
+	s.handle(w, r)
+}
+
+// handle is the function that handles the Alexa HTTP request for the Server.
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	id := s.track(r.RemoteAddr)
+	defer s.untrack(id)
+
 	// Verify certificate is good
-	cert, err := validations.ValidateCertificate(r)
+	cert, err := s.validator.ValidateCertificate(r)
 	if err != nil {
-		writeBadRequest(w)
+		s.reject(w, r, nil, err)
 		return
 	}
 
 	// Verify signature is good
-	body, err := validations.ValidateSignature(r, cert)
+	body, alg, err := s.validator.ValidateSignatureAlgorithm(r, cert)
 	if err != nil {
-		writeBadRequest(w)
+		s.reject(w, r, nil, err)
 		return
 	}
 
+	if s.signed != nil {
+		s.signed(r, alg)
+	}
+
 	var data json.RawMessage
 
 	err = json.Unmarshal(body, &data)
 	if err != nil {
-		writeBadRequest(w)
+		s.reject(w, r, nil, bodyError(err))
 		return
 	}
 
 	ev, err := parser.Parse(data)
 	if err != nil {
-		writeBadRequest(w)
+		s.reject(w, r, nil, bodyError(err))
+		return
+	}
+
+	s.describe(id, describeEvent(ev))
+
+	// Find the Skill the request is for
+	rt, err := s.route(ev)
+	if err != nil {
+		s.reject(w, r, ev, err)
 		return
 	}
 
 	// Make sure the request is good
-	if err = validations.ValidateRequest(ev); err != nil {
-		writeBadRequest(w)
+	if err = rt.validator.ValidateRequest(ev); err != nil {
+		s.reject(w, r, ev, err)
 		return
 	}
 
+	if ev.Request.Locale == "" {
+		ev.Request.Locale = rt.locale
+	}
+
+	// Errors the event handler responds to itself are still reported
+	var handled handledErrors
+	ctx := events.WithReporter(r.Context(), func(ev *parser.Event, err error) {
+		handled.add(err)
+		s.report(ev, err)
+	})
+
 	// Try and process the request
-	resp, err := Events.Event(ev)
+	resp, err := s.event(ctx, id, rt.events, ev)
 	if err != nil {
-		writeServerError(w)
-		return
+		if !handled.has(err) {
+			s.report(ev, err)
+		}
+
+		var perr *events.PanicError
+		if !errors.As(err, &perr) || s.panicResponse == nil {
+			writeServerError(w)
+			return
+		}
+
+		// Apologize so the session ends gracefully
+		resp = s.panicResponse
+	}
+
+	// Requests such as AudioPlayer events may not need a response
+	if resp == nil {
+		resp = response.New()
 	}
 
 	// Convert the data into bytes to send back
@@ -88,12 +760,59 @@ func Handler(w http.ResponseWriter, r *http.Request) {
 	w.Write(b)
 }
 
-// Run starts the server. It mounts the handler on /alexa.
+// event processes an event, giving up once the deadline has passed. If it
+// does, the timeout response is returned. The request stays in-flight until
+// the handler returns, even after giving up on it.
+func (s *Server) event(ctx context.Context, id uint64, h events.EventHandler, ev *parser.Event) (*response.Response, error) {
+	if s.deadline <= 0 {
+		return process(ctx, h, ev)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.deadline)
+	defer cancel()
+
+	type result struct {
+		resp *response.Response
+		err  error
+	}
+
+	s.retain(id)
+
+	done := make(chan result, 1)
+	go func() {
+		defer s.untrack(id)
+
+		resp, err := process(ctx, h, ev)
+		done <- result{resp, err}
+	}()
+
+	var res result
+	select {
+	case res = <-done:
+	case <-ctx.Done():
+		res.err = ctx.Err()
+	}
+
+	if errors.Is(res.err, context.DeadlineExceeded) && ctx.Err() == context.DeadlineExceeded {
+		return s.timeoutResponse, nil
+	}
+
+	return res.resp, res.err
+}
+
+// Run starts a server using Host, the package level validations, and the
+// event handler. It mounts the handler on /alexa of http.DefaultServeMux and
+// serves that, so any other handlers registered on it are served too.
 func Run(ev events.EventHandler) error {
 	Events = ev
 
-	http.HandleFunc("/alexa", Handler)
+	return New(withGlobals()).ListenAndServe()
+}
+
+// RunContext is the same as Run, but shuts down gracefully once ctx is done.
+// validations.DB is closed after the shutdown.
+func RunContext(ctx context.Context, ev events.EventHandler) error {
+	Events = ev
 
-	return http.ListenAndServe(Host, handlers.LoggingHandler(os.Stdout,
-		handlers.ProxyHeaders(http.DefaultServeMux)))
+	return New(withGlobals()).RunContext(ctx)
 }
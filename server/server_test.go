@@ -1,7 +1,25 @@
 package server
 
 import (
-	"github.com/boltdb/bolt"
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
 
 	"github.com/go-alexa/alexa/events"
 	"github.com/go-alexa/alexa/parser"
@@ -34,3 +52,648 @@ func ExampleServer() {
 
 	Run(ev)
 }
+
+func ExampleNew() {
+	d, err := bolt.Open("info.db", 0600, nil)
+	if err != nil {
+		panic(err)
+	}
+	defer d.Close()
+
+	ev := events.New().
+		Add("HelloWorld",
+			func(ev *parser.Event) (*response.Response, error) {
+				return response.New().
+					AddSpeech("Hello, world!"), nil
+			})
+
+	s := New(
+		WithAddr(":8080"),
+		WithAppID("amzn1.ask.skill.example"),
+		WithCertCache(validations.NewBoltCertCache(d)),
+		WithEvents(ev),
+	)
+
+	s.ListenAndServe()
+}
+
+// fakeValidator accepts every request.
+type fakeValidator struct{}
+
+func (fakeValidator) ValidateCertificate(r *http.Request) (*x509.Certificate, error) {
+	return nil, nil
+}
+
+func (fakeValidator) ValidateSignatureAlgorithm(r *http.Request, cert *x509.Certificate) ([]byte, x509.SignatureAlgorithm, error) {
+	body, err := ioutil.ReadAll(r.Body)
+	return body, x509.SHA256WithRSA, err
+}
+
+func (fakeValidator) ValidateRequest(ev *parser.Event) error {
+	return nil
+}
+
+const intentRequest = `{
+	"version": "1.0",
+	"session": {"sessionId": "session", "application": {"applicationId": "app"}},
+	"request": {
+		"type": "IntentRequest",
+		"requestId": "request",
+		"timestamp": "2017-01-01T00:00:00Z",
+		"intent": {"name": "HelloWorld"}
+	}
+}`
+
+// newTestServer creates a Server that accepts every request and responds to
+// HelloWorld with speech.
+func newTestServer(speech string, opts ...Option) *Server {
+	ev := events.New().
+		Add("HelloWorld",
+			func(ev *parser.Event) (*response.Response, error) {
+				return response.New().AddSpeech(speech), nil
+			})
+
+	s := New(append([]Option{WithEvents(ev)}, opts...)...)
+	s.validator = fakeValidator{}
+
+	return s
+}
+
+// post sends body to the Server and decodes the response.
+func post(t *testing.T, h http.Handler, path, body string) (int, *response.Response) {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	rec := httptest.NewRecorder()
+
+	h.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		return rec.Code, nil
+	}
+
+	var resp response.Response
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unable to decode response: %v", err)
+	}
+
+	return rec.Code, &resp
+}
+
+func TestServersAreIndependent(t *testing.T) {
+	first := newTestServer("first")
+	second := newTestServer("second", WithPath("/other"))
+
+	for _, tt := range []struct {
+		server *Server
+		path   string
+		speech string
+	}{
+		{first, DefaultPath, "first"},
+		{second, "/other", "second"},
+	} {
+		code, resp := post(t, tt.server, tt.path, intentRequest)
+		if code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, code)
+		}
+
+		if got := resp.Response.OutputSpeech.Text; got != tt.speech {
+			t.Errorf("expected speech %q, got %q", tt.speech, got)
+		}
+	}
+
+	if code, _ := post(t, second, DefaultPath, intentRequest); code != http.StatusNotFound {
+		t.Errorf("expected status %d, got %d", http.StatusNotFound, code)
+	}
+}
+
+// registerHealth registers a health check on http.DefaultServeMux once.
+var registerHealth sync.Once
+
+func TestGlobalsServeDefaultServeMux(t *testing.T) {
+	registerHealth.Do(func() {
+		http.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("ok"))
+		})
+	})
+
+	// Run serves the handler of the underlying http.Server
+	h := New(withGlobals(), WithLogger(log.New(ioutil.Discard, "", 0))).server().Handler
+
+	req := httptest.NewRequest(http.MethodGet, "/health", nil)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK || rec.Body.String() != "ok" {
+		t.Errorf("expected the health check, got %d %q", rec.Code, rec.Body.String())
+	}
+
+	// The last Server created for the globals handles Alexa requests
+	for _, speech := range []string{"first", "second"} {
+		s := New(withGlobals(), WithLogger(log.New(ioutil.Discard, "", 0)))
+		s.events = newTestServer(speech).events
+		s.validator = fakeValidator{}
+
+		code, resp := post(t, s.server().Handler, DefaultPath, intentRequest)
+		if code != http.StatusOK {
+			t.Errorf("expected status %d, got %d", http.StatusOK, code)
+			continue
+		}
+
+		if got := resp.Response.OutputSpeech.Text; got != speech {
+			t.Errorf("expected speech %q, got %q", speech, got)
+		}
+	}
+}
+
+func TestShutdownWaitsForInFlight(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	ev := events.New().
+		Add("HelloWorld",
+			func(ev *parser.Event) (*response.Response, error) {
+				close(started)
+				<-release
+				return response.New().AddSpeech("done"), nil
+			})
+
+	s := New(WithEvents(ev))
+	s.validator = fakeValidator{}
+
+	done := make(chan int)
+	go func() {
+		code, _ := post(t, s, DefaultPath, intentRequest)
+		done <- code
+	}()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	err := s.Shutdown(ctx)
+
+	var shutdownErr *ShutdownError
+	if !errors.As(err, &shutdownErr) {
+		t.Fatalf("expected a *ShutdownError, got %v", err)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected context.DeadlineExceeded, got %v", shutdownErr.Err)
+	}
+	if want := []string{"request IntentRequest HelloWorld"}; !reflect.DeepEqual(shutdownErr.InFlight, want) {
+		t.Errorf("expected in-flight %v, got %v", want, shutdownErr.InFlight)
+	}
+
+	close(release)
+	if code := <-done; code != http.StatusOK {
+		t.Errorf("expected status %d, got %d", http.StatusOK, code)
+	}
+
+	if err := s.Shutdown(context.Background()); err != nil {
+		t.Errorf("expected no error once drained, got %v", err)
+	}
+}
+
+// closeRecorder records if it was closed.
+type closeRecorder struct {
+	closed int32
+}
+
+func (c *closeRecorder) Close() error {
+	atomic.StoreInt32(&c.closed, 1)
+	return nil
+}
+
+// freeAddr finds a local address that is free to listen on.
+func freeAddr(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unable to listen: %v", err)
+	}
+	defer l.Close()
+
+	return l.Addr().String()
+}
+
+func TestRunContextDrains(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	ev := events.New().
+		Add("HelloWorld",
+			func(ev *parser.Event) (*response.Response, error) {
+				close(started)
+				<-release
+				return response.New().AddSpeech("done"), nil
+			})
+
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "certs.db"), 0600, nil)
+	if err != nil {
+		t.Fatalf("unable to open database: %v", err)
+	}
+	defer db.Close()
+
+	addr := freeAddr(t)
+	s := New(
+		WithAddr(addr),
+		WithEvents(ev),
+		WithCertCache(validations.NewBoltCertCache(db)),
+		WithLogger(log.New(ioutil.Discard, "", 0)),
+	)
+	s.validator = fakeValidator{}
+
+	// Anything the Server created itself is closed, but not the caller's
+	owned := &closeRecorder{}
+	s.closers = append(s.closers, owned)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	errc := make(chan error, 1)
+	go func() {
+		errc <- s.RunContext(ctx)
+	}()
+
+	codes := make(chan int, 1)
+	go func() {
+		for {
+			resp, err := http.Post("http://"+addr+DefaultPath, "application/json", strings.NewReader(intentRequest))
+			if err != nil {
+				// The server may not be listening yet
+				time.Sleep(10 * time.Millisecond)
+				continue
+			}
+			resp.Body.Close()
+
+			codes <- resp.StatusCode
+			return
+		}
+	}()
+	<-started
+
+	cancel()
+
+	select {
+	case err := <-errc:
+		t.Fatalf("expected RunContext to wait for the request, got %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if atomic.LoadInt32(&owned.closed) != 0 {
+		t.Error("expected nothing to be closed while the request is running")
+	}
+
+	close(release)
+
+	if code := <-codes; code != http.StatusOK {
+		t.Errorf("expected status %d, got %d", http.StatusOK, code)
+	}
+	if err := <-errc; err != nil {
+		t.Errorf("expected no error once drained, got %v", err)
+	}
+
+	if atomic.LoadInt32(&owned.closed) == 0 {
+		t.Error("expected what the Server created to be closed")
+	}
+	if err := db.View(func(tx *bolt.Tx) error { return nil }); err != nil {
+		t.Errorf("expected the caller's database to stay open, got %v", err)
+	}
+}
+
+// newIntentRequest creates an intent request for an application made now.
+func newIntentRequest(appID, locale string) string {
+	return fmt.Sprintf(`{
+		"version": "1.0",
+		"session": {"sessionId": "session", "application": {"applicationId": %q}},
+		"request": {
+			"type": "IntentRequest",
+			"requestId": "request",
+			"locale": %q,
+			"timestamp": %q,
+			"intent": {"name": "HelloWorld"}
+		}
+	}`, appID, locale, time.Now().UTC().Format(time.RFC3339))
+}
+
+func TestSkillRouting(t *testing.T) {
+	skill := func(appID, locale string) Skill {
+		return Skill{
+			AppID: appID,
+			Events: events.New().
+				Add("HelloWorld",
+					func(ev *parser.Event) (*response.Response, error) {
+						return response.New().
+							AddSpeech(appID + " " + ev.Request.Locale), nil
+					}),
+			DefaultLocale: locale,
+		}
+	}
+
+	s := New(
+		WithSkill(skill("first", "en-US")),
+		WithSkill(skill("second", "de-DE")),
+	)
+	s.validator = fakeValidator{}
+
+	for _, tt := range []struct {
+		body   string
+		code   int
+		speech string
+	}{
+		{newIntentRequest("first", ""), http.StatusOK, "first en-US"},
+		{newIntentRequest("second", ""), http.StatusOK, "second de-DE"},
+		{newIntentRequest("second", "en-GB"), http.StatusOK, "second en-GB"},
+		{newIntentRequest("unknown", ""), http.StatusBadRequest, ""},
+	} {
+		code, resp := post(t, s, DefaultPath, tt.body)
+		if code != tt.code {
+			t.Errorf("expected status %d, got %d", tt.code, code)
+			continue
+		}
+
+		if resp != nil && resp.Response.OutputSpeech.Text != tt.speech {
+			t.Errorf("expected speech %q, got %q", tt.speech, resp.Response.OutputSpeech.Text)
+		}
+	}
+}
+
+// fakeSignatures accepts every certificate and signature, but validates
+// requests with a Validator.
+type fakeSignatures struct {
+	*validations.Validator
+}
+
+func (fakeSignatures) ValidateCertificate(r *http.Request) (*x509.Certificate, error) {
+	return fakeValidator{}.ValidateCertificate(r)
+}
+
+func (fakeSignatures) ValidateSignatureAlgorithm(r *http.Request, cert *x509.Certificate) ([]byte, x509.SignatureAlgorithm, error) {
+	return fakeValidator{}.ValidateSignatureAlgorithm(r, cert)
+}
+
+func TestReplayStore(t *testing.T) {
+	skill := Skill{
+		AppID: "skill",
+		Events: events.New().
+			Add("HelloWorld",
+				func(ev *parser.Event) (*response.Response, error) {
+					return response.New(), nil
+				}),
+	}
+
+	// The Validator's store is used by Skills too
+	v := validations.NewValidator("app")
+	v.Replay = validations.NewMemoryReplayStore()
+
+	withValidator := New(WithValidator(v), WithSkill(skill))
+	withValidator.validator = fakeSignatures{v}
+
+	withStore := New(
+		WithEvents(skill.Events),
+		WithAppID("app"),
+		WithReplayStore(validations.NewMemoryReplayStore()))
+	withStore.validator = fakeSignatures{withStore.validator.(*validations.Validator)}
+
+	for _, tt := range []struct {
+		name   string
+		server *Server
+		appID  string
+	}{
+		{"store", withStore, "app"},
+		{"validator", withValidator, "skill"},
+	} {
+		body := newIntentRequest(tt.appID, "")
+
+		if code, _ := post(t, tt.server, DefaultPath, body); code != http.StatusOK {
+			t.Errorf("%s: expected status %d, got %d", tt.name, http.StatusOK, code)
+		}
+
+		if code, _ := post(t, tt.server, DefaultPath, body); code != http.StatusBadRequest {
+			t.Errorf("%s: expected a replay to get status %d, got %d", tt.name, http.StatusBadRequest, code)
+		}
+	}
+}
+
+func TestSignatureReporter(t *testing.T) {
+	var got x509.SignatureAlgorithm
+
+	s := newTestServer("hello", WithSignatureReporter(func(r *http.Request, alg x509.SignatureAlgorithm) {
+		got = alg
+	}))
+
+	if code, _ := post(t, s, DefaultPath, intentRequest); code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, code)
+	}
+
+	if got != x509.SHA256WithRSA {
+		t.Errorf("expected %v to be reported, got %v", x509.SHA256WithRSA, got)
+	}
+}
+
+func TestDeadlineTimeoutResponse(t *testing.T) {
+	ev := events.New().
+		AddContext("HelloWorld",
+			func(ctx context.Context, ev *parser.Event) (*response.Response, error) {
+				<-ctx.Done()
+				return nil, ctx.Err()
+			})
+
+	s := New(
+		WithEvents(ev),
+		WithDeadline(50*time.Millisecond),
+		WithTimeoutResponse(response.New().AddSpeech("too slow")),
+	)
+	s.validator = fakeValidator{}
+
+	code, resp := post(t, s, DefaultPath, intentRequest)
+	if code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, code)
+	}
+
+	if got := resp.Response.OutputSpeech.Text; got != "too slow" {
+		t.Errorf("expected speech %q, got %q", "too slow", got)
+	}
+}
+
+func TestDeadlineKeepsHandlerInFlight(t *testing.T) {
+	release := make(chan struct{})
+	returned := make(chan struct{})
+
+	// The handler ignores its context, so it outlives the deadline
+	ev := events.New().
+		Add("HelloWorld",
+			func(ev *parser.Event) (*response.Response, error) {
+				defer close(returned)
+				<-release
+				return response.New(), nil
+			})
+
+	s := New(WithEvents(ev), WithDeadline(20*time.Millisecond))
+	s.validator = fakeValidator{}
+
+	if code, _ := post(t, s, DefaultPath, intentRequest); code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, code)
+	}
+
+	if want := []string{"request IntentRequest HelloWorld"}; !reflect.DeepEqual(s.InFlight(), want) {
+		t.Errorf("expected in-flight %v after the deadline, got %v", want, s.InFlight())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := s.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected shutdown to wait for the handler, got %v", err)
+	}
+
+	close(release)
+	<-returned
+
+	if err := s.Shutdown(context.Background()); err != nil {
+		t.Errorf("expected no error once the handler returned, got %v", err)
+	}
+}
+
+func TestPanicRecovery(t *testing.T) {
+	for _, deadline := range []time.Duration{0, DefaultDeadline} {
+		var report *ErrorReport
+
+		// A zero Handler has no ErrorHandler, so the panic reaches the Server
+		ev := &events.Handler{
+			IntentHandlers: map[string]events.IntentFunc{
+				"HelloWorld": func(ev *parser.Event) (*response.Response, error) {
+					panic("oops")
+				},
+			},
+		}
+
+		s := New(
+			WithEvents(ev),
+			WithDeadline(deadline),
+			WithErrorReporter(func(r *ErrorReport) {
+				report = r
+			}),
+		)
+		s.validator = fakeValidator{}
+
+		code, resp := post(t, s, DefaultPath, intentRequest)
+		if code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, code)
+		}
+
+		if got := resp.Response.OutputSpeech.Text; got != events.ErrorSpeech {
+			t.Errorf("expected speech %q, got %q", events.ErrorSpeech, got)
+		}
+
+		if report == nil || !report.Panicked() {
+			t.Fatalf("expected a panic to be reported, got %+v", report)
+		}
+		if report.RequestID != "request" || report.Intent != "HelloWorld" || len(report.Stack) == 0 {
+			t.Errorf("expected request ID, intent and stack, got %+v", report)
+		}
+	}
+}
+
+func TestPanicRecoveryWithErrorHandler(t *testing.T) {
+	for _, deadline := range []time.Duration{0, DefaultDeadline} {
+		var reports []*ErrorReport
+
+		// The default ErrorHandler responds, but the panic is still reported
+		ev := events.New().
+			Add("HelloWorld",
+				func(ev *parser.Event) (*response.Response, error) {
+					panic("oops")
+				})
+
+		s := New(
+			WithEvents(ev),
+			WithDeadline(deadline),
+			WithErrorReporter(func(r *ErrorReport) {
+				reports = append(reports, r)
+			}),
+		)
+		s.validator = fakeValidator{}
+
+		code, resp := post(t, s, DefaultPath, intentRequest)
+		if code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, code)
+		}
+
+		if got := resp.Response.OutputSpeech.Text; got != events.ErrorSpeech {
+			t.Errorf("expected speech %q, got %q", events.ErrorSpeech, got)
+		}
+
+		if len(reports) != 1 || !reports[0].Panicked() {
+			t.Fatalf("expected a panic to be reported once, got %+v", reports)
+		}
+		if reports[0].Intent != "HelloWorld" || len(reports[0].Stack) == 0 {
+			t.Errorf("expected intent and stack, got %+v", reports[0])
+		}
+	}
+}
+
+func TestErrorReportedOnce(t *testing.T) {
+	var reports []*ErrorReport
+
+	errFailed := errors.New("failed")
+
+	// The ErrorHandler gives the error back, so it reaches the Server too
+	ev := events.New().
+		OnError(func(ev *parser.Event, err error) (*response.Response, error) {
+			return nil, err
+		}).
+		Add("HelloWorld",
+			func(ev *parser.Event) (*response.Response, error) {
+				return nil, errFailed
+			})
+
+	s := New(
+		WithEvents(ev),
+		WithErrorReporter(func(r *ErrorReport) {
+			reports = append(reports, r)
+		}),
+	)
+	s.validator = fakeValidator{}
+
+	if code, _ := post(t, s, DefaultPath, intentRequest); code != http.StatusInternalServerError {
+		t.Errorf("expected status %d, got %d", http.StatusInternalServerError, code)
+	}
+
+	if len(reports) != 1 || reports[0].Err != errFailed {
+		t.Errorf("expected the error to be reported once, got %+v", reports)
+	}
+}
+
+func TestRejectionReporter(t *testing.T) {
+	var rejections []*Rejection
+
+	s := New(
+		WithSkill(Skill{AppID: "app", Events: events.New()}),
+		WithRejectionReporter(func(r *Rejection) {
+			rejections = append(rejections, r)
+		}),
+	)
+	s.validator = fakeValidator{}
+
+	for _, tt := range []struct {
+		body  string
+		stage validations.Stage
+		err   error
+	}{
+		{"{", validations.StageBody, nil},
+		{newIntentRequest("unknown", ""), validations.StageApplication, ErrUnknownApp},
+		{intentRequest, validations.StageTimestamp, validations.ErrOutsideTime},
+	} {
+		rejections = nil
+
+		if code, _ := post(t, s, DefaultPath, tt.body); code != http.StatusBadRequest {
+			t.Errorf("expected status %d, got %d", http.StatusBadRequest, code)
+		}
+
+		if len(rejections) != 1 {
+			t.Errorf("expected 1 rejection, got %d", len(rejections))
+			continue
+		}
+
+		if r := rejections[0]; r.Stage != tt.stage || (tt.err != nil && !errors.Is(r.Err, tt.err)) {
+			t.Errorf("expected %s rejection for %v, got %s for %v", tt.stage, tt.err, r.Stage, r.Err)
+		}
+	}
+}
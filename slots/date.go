@@ -0,0 +1,229 @@
+package slots
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Granularity is how much time a Date covers.
+type Granularity string
+
+// Granularities of an AMAZON.DATE value.
+const (
+	// Present is right now, from "PRESENT_REF".
+	Present Granularity = "present"
+	// Day is a single day, such as "2015-11-24".
+	Day Granularity = "day"
+	// Week is a Monday to Sunday ISO week, such as "2015-W48".
+	Week Granularity = "week"
+	// Weekend is the Saturday and Sunday of a week, such as "2015-W48-WE".
+	Weekend Granularity = "weekend"
+	// Month is a month, such as "2015-11".
+	Month Granularity = "month"
+	// Season is a season, such as "2015-WI".
+	Season Granularity = "season"
+	// Year is a year, such as "2015".
+	Year Granularity = "year"
+	// Decade is a decade, such as "201X".
+	Decade Granularity = "decade"
+)
+
+// seasons are the months each season starts in. They are the meteorological
+// seasons of the northern hemisphere, so winter starts in December and ends
+// the next year.
+var seasons = map[string]time.Month{
+	"SP": time.March,
+	"SU": time.June,
+	"FA": time.September,
+	"WI": time.December,
+}
+
+// Date is the value of an AMAZON.DATE slot. It is the range of time from
+// Start until End, in the user's time zone.
+type Date struct {
+	// Start is the start of the range.
+	Start time.Time
+	// End is the end of the range. It is not included, except for Present
+	// where it is the same as Start.
+	End time.Time
+	// Granularity is how much time the range covers.
+	Granularity Granularity
+}
+
+// Contains is if a time is within the range.
+func (d Date) Contains(t time.Time) bool {
+	if d.Granularity == Present {
+		return t.Equal(d.Start)
+	}
+
+	return !t.Before(d.Start) && t.Before(d.End)
+}
+
+// ParseDate parses the value of an AMAZON.DATE slot. Parts that were not
+// said, such as the year of "XXXX-12-25" or the month of "2018-XX-12", are
+// chosen so the date is the next one that has not already passed.
+func (p *Parser) ParseDate(value string) (Date, error) {
+	if value == "" {
+		return Date{}, ErrNoValue
+	}
+
+	now := p.now()
+
+	if value == "PRESENT_REF" {
+		return Date{Start: now, End: now, Granularity: Present}, nil
+	}
+
+	parts := strings.Split(value, "-")
+
+	// A decade only has a year such as "201X"
+	if len(parts) == 1 && len(value) == 4 && strings.HasSuffix(value, "X") {
+		decade, err := strconv.Atoi(value[:3])
+		if err != nil {
+			return Date{}, invalid("AMAZON.DATE", value)
+		}
+
+		start := time.Date(decade*10, time.January, 1, 0, 0, 0, 0, now.Location())
+		return Date{Start: start, End: start.AddDate(10, 0, 0), Granularity: Decade}, nil
+	}
+
+	// Look far enough ahead for a year with a date such as the 29th of
+	// February, even across a century that is not a leap year
+	thisYear := now.Year()
+	years, ok := candidates(parts[0], 4, thisYear, thisYear+1, thisYear+2,
+		thisYear+3, thisYear+4, thisYear+5, thisYear+6, thisYear+7, thisYear+8)
+	if !ok {
+		return Date{}, invalid("AMAZON.DATE", value)
+	}
+
+	var dates []Date
+
+	// Skip years without the date, such as the 29th of February
+	for _, year := range years {
+		if d, ok := dateInYear(year, parts[1:], now.Location()); ok {
+			dates = append(dates, d...)
+		}
+	}
+
+	if len(dates) == 0 {
+		return Date{}, invalid("AMAZON.DATE", value)
+	}
+
+	return next(dates, now), nil
+}
+
+// dateInYear gets every date a value could be in a year, using the parts of
+// the value after the year.
+func dateInYear(year int, parts []string, loc *time.Location) ([]Date, bool) {
+	switch {
+	case len(parts) == 0:
+		start := time.Date(year, time.January, 1, 0, 0, 0, 0, loc)
+		return []Date{{Start: start, End: start.AddDate(1, 0, 0), Granularity: Year}}, true
+
+	case len(parts) == 1 && seasons[parts[0]] != 0:
+		start := time.Date(year, seasons[parts[0]], 1, 0, 0, 0, 0, loc)
+		return []Date{{Start: start, End: start.AddDate(0, 3, 0), Granularity: Season}}, true
+
+	case len(parts) <= 2 && strings.HasPrefix(parts[0], "W"):
+		week, err := strconv.Atoi(parts[0][1:])
+		if err != nil || len(parts[0]) != 3 {
+			return nil, false
+		}
+
+		start, ok := isoWeek(year, week, loc)
+		if !ok {
+			return nil, false
+		}
+
+		if len(parts) == 1 {
+			return []Date{{Start: start, End: start.AddDate(0, 0, 7), Granularity: Week}}, true
+		}
+
+		if parts[1] != "WE" {
+			return nil, false
+		}
+
+		start = start.AddDate(0, 0, 5)
+		return []Date{{Start: start, End: start.AddDate(0, 0, 2), Granularity: Weekend}}, true
+	}
+
+	months, ok := candidates(parts[0], 2, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12)
+	if !ok || len(parts) > 2 {
+		return nil, false
+	}
+
+	var dates []Date
+
+	for _, month := range months {
+		if month < 1 || month > 12 {
+			return nil, false
+		}
+
+		start := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, loc)
+
+		if len(parts) == 1 {
+			dates = append(dates, Date{Start: start, End: start.AddDate(0, 1, 0), Granularity: Month})
+			continue
+		}
+
+		day, err := strconv.Atoi(parts[1])
+		if err != nil || len(parts[1]) != 2 || day < 1 || day > 31 {
+			return nil, false
+		}
+
+		// Skip months without the day, such as the 31st of April
+		start = start.AddDate(0, 0, day-1)
+		if start.Month() != time.Month(month) {
+			continue
+		}
+
+		dates = append(dates, Date{Start: start, End: start.AddDate(0, 0, 1), Granularity: Day})
+	}
+
+	return dates, len(dates) > 0
+}
+
+// candidates gets the numbers a part of a date could be. A part that was not
+// said is all X, so it could be any of the choices.
+func candidates(part string, size int, choices ...int) ([]int, bool) {
+	if len(part) != size {
+		return nil, false
+	}
+
+	if part == strings.Repeat("X", size) {
+		return choices, true
+	}
+
+	n, err := strconv.Atoi(part)
+	if err != nil {
+		return nil, false
+	}
+
+	return []int{n}, true
+}
+
+// isoWeek gets the Monday that starts an ISO week.
+func isoWeek(year, week int, loc *time.Location) (time.Time, bool) {
+	// The first week is the one with the 4th of January in it
+	jan4 := time.Date(year, time.January, 4, 0, 0, 0, 0, loc)
+	monday := jan4.AddDate(0, 0, -((int(jan4.Weekday()) + 6) % 7))
+
+	start := monday.AddDate(0, 0, (week-1)*7)
+	if y, w := start.ISOWeek(); week < 1 || y != year || w != week {
+		return time.Time{}, false
+	}
+
+	return start, true
+}
+
+// next gets the first date that has not already passed, or the last one if
+// they all have.
+func next(dates []Date, now time.Time) Date {
+	for _, d := range dates {
+		if d.End.After(now) {
+			return d
+		}
+	}
+
+	return dates[len(dates)-1]
+}
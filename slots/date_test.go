@@ -0,0 +1,91 @@
+package slots
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// newTestParser creates a Parser in New York at a fixed time, Wednesday the
+// 14th of March 2018.
+func newTestParser(t *testing.T) *Parser {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("unable to load time zone: %v", err)
+	}
+
+	p := NewParser(loc)
+	p.Now = func() time.Time {
+		return time.Date(2018, time.March, 14, 15, 30, 0, 0, loc)
+	}
+
+	return p
+}
+
+func TestParseDate(t *testing.T) {
+	p := newTestParser(t)
+	loc := p.Location
+
+	day := func(y int, m time.Month, d int) time.Time {
+		return time.Date(y, m, d, 0, 0, 0, 0, loc)
+	}
+
+	for _, tt := range []struct {
+		value       string
+		start, end  time.Time
+		granularity Granularity
+	}{
+		{"2015-11-24", day(2015, 11, 24), day(2015, 11, 25), Day},
+		{"2015-W48", day(2015, 11, 23), day(2015, 11, 30), Week},
+		{"2015-W48-WE", day(2015, 11, 28), day(2015, 11, 30), Weekend},
+		{"2015-W01", day(2014, 12, 29), day(2015, 1, 5), Week},
+		{"2015-11", day(2015, 11, 1), day(2015, 12, 1), Month},
+		{"2015", day(2015, 1, 1), day(2016, 1, 1), Year},
+		{"201X", day(2010, 1, 1), day(2020, 1, 1), Decade},
+		{"2017-WI", day(2017, 12, 1), day(2018, 3, 1), Season},
+		{"2018-SU", day(2018, 6, 1), day(2018, 9, 1), Season},
+		{"XXXX-12-25", day(2018, 12, 25), day(2018, 12, 26), Day},
+		{"XXXX-01-01", day(2019, 1, 1), day(2019, 1, 2), Day},
+		{"XXXX-03-14", day(2018, 3, 14), day(2018, 3, 15), Day},
+		{"2018-XX-12", day(2018, 4, 12), day(2018, 4, 13), Day},
+		{"2018-XX-31", day(2018, 3, 31), day(2018, 4, 1), Day},
+		{"XXXX-02", day(2019, 2, 1), day(2019, 3, 1), Month},
+		{"2016-XX-12", day(2016, 12, 12), day(2016, 12, 13), Day},
+		{"XXXX-02-29", day(2020, 2, 29), day(2020, 3, 1), Day},
+		{"XXXX-W53", day(2020, 12, 28), day(2021, 1, 4), Week},
+	} {
+		d, err := p.ParseDate(tt.value)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.value, err)
+			continue
+		}
+
+		if !d.Start.Equal(tt.start) || !d.End.Equal(tt.end) || d.Granularity != tt.granularity {
+			t.Errorf("%s: expected %s %v to %v, got %s %v to %v", tt.value,
+				tt.granularity, tt.start, tt.end, d.Granularity, d.Start, d.End)
+		}
+	}
+}
+
+func TestParseDatePresent(t *testing.T) {
+	p := newTestParser(t)
+
+	d, err := p.ParseDate("PRESENT_REF")
+	if err != nil || d.Granularity != Present || !d.Start.Equal(p.Now()) || !d.Contains(p.Now()) {
+		t.Errorf("expected the present, got %+v, %v", d, err)
+	}
+}
+
+func TestParseDateInvalid(t *testing.T) {
+	p := newTestParser(t)
+
+	if _, err := p.ParseDate(""); err != ErrNoValue {
+		t.Errorf("expected %v, got %v", ErrNoValue, err)
+	}
+
+	for _, value := range []string{"?", "2015-13", "2015-02-30", "2015-W54", "2015-W48-XX", "2015-XY", "15-11-24", "2015-11-24-01", "XXXX-02-30"} {
+		if _, err := p.ParseDate(value); !errors.Is(err, ErrInvalid) {
+			t.Errorf("%s: expected %v, got %v", value, ErrInvalid, err)
+		}
+	}
+}
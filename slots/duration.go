@@ -0,0 +1,108 @@
+package slots
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Duration is the value of an AMAZON.DURATION slot, such as "PT1H30M". Years,
+// months, weeks and days vary in length, so they are kept separate.
+type Duration struct {
+	Years   int
+	Months  int
+	Weeks   int
+	Days    int
+	Hours   int
+	Minutes int
+	Seconds float64
+}
+
+// AddTo adds the duration to a time. Years, months, weeks and days are added
+// to the calendar date in t's time zone.
+func (d Duration) AddTo(t time.Time) time.Time {
+	t = t.AddDate(d.Years, d.Months, d.Weeks*7+d.Days)
+
+	return t.Add(time.Duration(d.Hours)*time.Hour +
+		time.Duration(d.Minutes)*time.Minute +
+		time.Duration(d.Seconds*float64(time.Second)))
+}
+
+// Length gets how long the duration is when it starts at from.
+func (d Duration) Length(from time.Time) time.Duration {
+	return d.AddTo(from).Sub(from)
+}
+
+// ParseDuration parses the value of an AMAZON.DURATION slot, which is an
+// ISO-8601 duration such as "P2D" or "PT1H30M".
+func ParseDuration(value string) (Duration, error) {
+	var d Duration
+
+	if value == "" {
+		return d, ErrNoValue
+	}
+
+	if !strings.HasPrefix(value, "P") || strings.HasSuffix(value, "T") || len(value) < 3 {
+		return d, invalid("AMAZON.DURATION", value)
+	}
+
+	inTime := false
+	number := ""
+
+	for _, c := range value[1:] {
+		switch {
+		case c >= '0' && c <= '9' || c == '.':
+			number += string(c)
+			continue
+		case c == 'T' && !inTime && number == "":
+			inTime = true
+			continue
+		}
+
+		// Every other character is the unit of the number before it
+		if number == "" {
+			return d, invalid("AMAZON.DURATION", value)
+		}
+
+		if c == 'S' && inTime {
+			s, err := strconv.ParseFloat(number, 64)
+			if err != nil {
+				return d, invalid("AMAZON.DURATION", value)
+			}
+
+			d.Seconds = s
+			number = ""
+			continue
+		}
+
+		n, err := strconv.Atoi(number)
+		if err != nil {
+			return d, invalid("AMAZON.DURATION", value)
+		}
+
+		switch {
+		case c == 'Y' && !inTime:
+			d.Years = n
+		case c == 'M' && !inTime:
+			d.Months = n
+		case c == 'W' && !inTime:
+			d.Weeks = n
+		case c == 'D' && !inTime:
+			d.Days = n
+		case c == 'H' && inTime:
+			d.Hours = n
+		case c == 'M' && inTime:
+			d.Minutes = n
+		default:
+			return d, invalid("AMAZON.DURATION", value)
+		}
+
+		number = ""
+	}
+
+	if number != "" {
+		return d, invalid("AMAZON.DURATION", value)
+	}
+
+	return d, nil
+}
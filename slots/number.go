@@ -0,0 +1,49 @@
+package slots
+
+import (
+	"strconv"
+)
+
+// FourDigitNumber is the value of an AMAZON.FOUR_DIGIT_NUMBER slot. It is kept
+// as a string, as leading zeros matter for things like PINs.
+type FourDigitNumber string
+
+// Int gets the number as an int.
+func (n FourDigitNumber) Int() int {
+	i, _ := strconv.Atoi(string(n))
+	return i
+}
+
+// ParseNumber parses the value of an AMAZON.NUMBER slot.
+func ParseNumber(value string) (int64, error) {
+	if value == "" {
+		return 0, ErrNoValue
+	}
+
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, invalid("AMAZON.NUMBER", value)
+	}
+
+	return n, nil
+}
+
+// ParseFourDigitNumber parses the value of an AMAZON.FOUR_DIGIT_NUMBER slot.
+// It must be exactly four digits.
+func ParseFourDigitNumber(value string) (FourDigitNumber, error) {
+	if value == "" {
+		return "", ErrNoValue
+	}
+
+	if len(value) != 4 {
+		return "", invalid("AMAZON.FOUR_DIGIT_NUMBER", value)
+	}
+
+	for _, c := range value {
+		if c < '0' || c > '9' {
+			return "", invalid("AMAZON.FOUR_DIGIT_NUMBER", value)
+		}
+	}
+
+	return FourDigitNumber(value), nil
+}
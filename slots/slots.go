@@ -0,0 +1,82 @@
+// Package slots parses the values of Amazon's built-in slot types, such as
+// AMAZON.DATE and AMAZON.DURATION, into typed values.
+package slots
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-alexa/alexa/parser"
+)
+
+var (
+	// ErrNoValue means the slot was not filled.
+	ErrNoValue = errors.New("slot has no value")
+	// ErrInvalid means the value was not valid for the slot type, such as
+	// "?" when Alexa did not understand a number.
+	ErrInvalid = errors.New("slot value is not valid")
+)
+
+// invalid creates an error for a value that is not valid for a slot type.
+func invalid(slotType, value string) error {
+	return fmt.Errorf("%w: %q is not an %s", ErrInvalid, value, slotType)
+}
+
+// Parser parses slot values whose meaning depends on the current time, such
+// as "PRESENT_REF" or "2018-XX-12", in the user's time zone.
+type Parser struct {
+	// Now gets the current time. If nil, time.Now is used.
+	Now func() time.Time
+	// Location is the user's time zone. If nil, UTC is used.
+	Location *time.Location
+}
+
+// NewParser creates a new Parser for a time zone.
+func NewParser(loc *time.Location) *Parser {
+	return &Parser{Location: loc}
+}
+
+// location gets the user's time zone.
+func (p *Parser) location() *time.Location {
+	if p.Location == nil {
+		return time.UTC
+	}
+
+	return p.Location
+}
+
+// now gets the current time in the user's time zone.
+func (p *Parser) now() time.Time {
+	now := time.Now
+	if p.Now != nil {
+		now = p.Now
+	}
+
+	return now().In(p.location())
+}
+
+// Date parses the value of an AMAZON.DATE slot.
+func (p *Parser) Date(slot parser.Slot) (Date, error) {
+	return p.ParseDate(slot.Value)
+}
+
+// Time parses the value of an AMAZON.TIME slot.
+func (p *Parser) Time(slot parser.Slot) (Time, error) {
+	return p.ParseTime(slot.Value)
+}
+
+// Duration parses the value of an AMAZON.DURATION slot.
+func (p *Parser) Duration(slot parser.Slot) (Duration, error) {
+	return ParseDuration(slot.Value)
+}
+
+// Number parses the value of an AMAZON.NUMBER slot.
+func (p *Parser) Number(slot parser.Slot) (int64, error) {
+	return ParseNumber(slot.Value)
+}
+
+// FourDigitNumber parses the value of an AMAZON.FOUR_DIGIT_NUMBER slot.
+func (p *Parser) FourDigitNumber(slot parser.Slot) (FourDigitNumber, error) {
+	return ParseFourDigitNumber(slot.Value)
+}
@@ -0,0 +1,74 @@
+package slots
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-alexa/alexa/parser"
+)
+
+func TestParseDuration(t *testing.T) {
+	for _, tt := range []struct {
+		value string
+		want  Duration
+	}{
+		{"PT1H30M", Duration{Hours: 1, Minutes: 30}},
+		{"P2D", Duration{Days: 2}},
+		{"P1W", Duration{Weeks: 1}},
+		{"PT10S", Duration{Seconds: 10}},
+		{"PT0.5S", Duration{Seconds: 0.5}},
+		{"P1Y2M10DT2H30M", Duration{Years: 1, Months: 2, Days: 10, Hours: 2, Minutes: 30}},
+	} {
+		got, err := ParseDuration(tt.value)
+		if err != nil || got != tt.want {
+			t.Errorf("%s: expected %+v, got %+v, %v", tt.value, tt.want, got, err)
+		}
+	}
+
+	for _, value := range []string{"?", "P", "PT", "P1H", "PT1D", "1H", "P1DT", "PT1H30"} {
+		if _, err := ParseDuration(value); !errors.Is(err, ErrInvalid) {
+			t.Errorf("%s: expected %v, got %v", value, ErrInvalid, err)
+		}
+	}
+}
+
+func TestDurationAddTo(t *testing.T) {
+	from := time.Date(2018, time.January, 31, 12, 0, 0, 0, time.UTC)
+
+	d := Duration{Months: 1, Hours: 1, Minutes: 30}
+	if got, want := d.AddTo(from), time.Date(2018, time.March, 3, 13, 30, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	if got := (Duration{Weeks: 1, Days: 1}).Length(from); got != 8*24*time.Hour {
+		t.Errorf("expected 8 days, got %v", got)
+	}
+}
+
+func TestParseNumbers(t *testing.T) {
+	p := NewParser(nil)
+
+	if n, err := p.Number(parser.Slot{Value: "-42"}); err != nil || n != -42 {
+		t.Errorf("expected -42, got %d, %v", n, err)
+	}
+
+	if _, err := p.Number(parser.Slot{Value: "?"}); !errors.Is(err, ErrInvalid) {
+		t.Errorf("expected %v, got %v", ErrInvalid, err)
+	}
+
+	if _, err := p.Number(parser.Slot{}); err != ErrNoValue {
+		t.Errorf("expected %v, got %v", ErrNoValue, err)
+	}
+
+	pin, err := p.FourDigitNumber(parser.Slot{Value: "0123"})
+	if err != nil || pin != "0123" || pin.Int() != 123 {
+		t.Errorf("expected 0123, got %q, %v", pin, err)
+	}
+
+	for _, value := range []string{"123", "12345", "12a4"} {
+		if _, err := ParseFourDigitNumber(value); !errors.Is(err, ErrInvalid) {
+			t.Errorf("%s: expected %v, got %v", value, ErrInvalid, err)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package slots
+
+import (
+	"time"
+)
+
+// Period is the part of the day an AMAZON.TIME value is for.
+type Period string
+
+// Periods of an AMAZON.TIME value.
+const (
+	// Exact is an exact time, such as "14:30".
+	Exact Period = ""
+	// Morning is "MO", from 6:00 until 12:00.
+	Morning Period = "MO"
+	// Afternoon is "AF", from 12:00 until 18:00.
+	Afternoon Period = "AF"
+	// Evening is "EV", from 18:00 until 21:00.
+	Evening Period = "EV"
+	// Night is "NI", from 21:00 until 6:00 the next day.
+	Night Period = "NI"
+)
+
+// periods are the hours each Period starts and ends.
+var periods = map[Period][2]int{
+	Morning:   {6, 12},
+	Afternoon: {12, 18},
+	Evening:   {18, 21},
+	Night:     {21, 30},
+}
+
+// Time is the value of an AMAZON.TIME slot on the current day, in the user's
+// time zone. For an Exact time, Start and End are the same.
+type Time struct {
+	// Start is when the time starts.
+	Start time.Time
+	// End is when the time ends.
+	End time.Time
+	// Period is the part of the day the time is for, or Exact.
+	Period Period
+}
+
+// On gets the time on another day, such as the value of an AMAZON.DATE slot.
+func (t Time) On(day time.Time) Time {
+	if t.Period != Exact {
+		return period(day, t.Period)
+	}
+
+	y, m, d := day.Date()
+	at := time.Date(y, m, d, t.Start.Hour(), t.Start.Minute(), t.Start.Second(), 0, day.Location())
+
+	return Time{Start: at, End: at, Period: Exact}
+}
+
+// period gets when a Period is on a day.
+func period(day time.Time, p Period) Time {
+	y, m, d := day.Date()
+	hours := periods[p]
+
+	return Time{
+		Start:  time.Date(y, m, d, hours[0], 0, 0, 0, day.Location()),
+		End:    time.Date(y, m, d, hours[1], 0, 0, 0, day.Location()),
+		Period: p,
+	}
+}
+
+// ParseTime parses the value of an AMAZON.TIME slot, such as "14:30" or "EV".
+func (p *Parser) ParseTime(value string) (Time, error) {
+	if value == "" {
+		return Time{}, ErrNoValue
+	}
+
+	now := p.now()
+
+	if _, ok := periods[Period(value)]; ok {
+		return period(now, Period(value)), nil
+	}
+
+	for _, layout := range []string{"15:04", "15:04:05"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return Time{Start: t, Period: Exact}.On(now), nil
+		}
+	}
+
+	return Time{}, invalid("AMAZON.TIME", value)
+}
@@ -0,0 +1,60 @@
+package slots
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseTime(t *testing.T) {
+	p := newTestParser(t)
+	loc := p.Location
+
+	at := func(d, h, m int) time.Time {
+		return time.Date(2018, time.March, d, h, m, 0, 0, loc)
+	}
+
+	for _, tt := range []struct {
+		value      string
+		start, end time.Time
+		period     Period
+	}{
+		{"14:30", at(14, 14, 30), at(14, 14, 30), Exact},
+		{"07:05", at(14, 7, 5), at(14, 7, 5), Exact},
+		{"MO", at(14, 6, 0), at(14, 12, 0), Morning},
+		{"AF", at(14, 12, 0), at(14, 18, 0), Afternoon},
+		{"EV", at(14, 18, 0), at(14, 21, 0), Evening},
+		{"NI", at(14, 21, 0), at(15, 6, 0), Night},
+	} {
+		got, err := p.ParseTime(tt.value)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.value, err)
+			continue
+		}
+
+		if !got.Start.Equal(tt.start) || !got.End.Equal(tt.end) || got.Period != tt.period {
+			t.Errorf("%s: expected %q %v to %v, got %q %v to %v", tt.value,
+				tt.period, tt.start, tt.end, got.Period, got.Start, got.End)
+		}
+	}
+
+	if _, err := p.ParseTime("25:00"); err == nil {
+		t.Error("expected an error for an invalid time")
+	}
+}
+
+func TestTimeOn(t *testing.T) {
+	p := newTestParser(t)
+
+	// The clocks change in New York on the 11th of March 2018
+	day := time.Date(2018, time.March, 11, 0, 0, 0, 0, p.Location)
+
+	evening, _ := p.ParseTime("EV")
+	if got := evening.On(day); got.Start.Hour() != 18 || got.End.Hour() != 21 || got.Start.Day() != 11 {
+		t.Errorf("expected the evening of the 11th, got %v to %v", got.Start, got.End)
+	}
+
+	exact, _ := p.ParseTime("01:15")
+	if got := exact.On(day); got.Start.Hour() != 1 || got.Start.Minute() != 15 || got.Start.Day() != 11 {
+		t.Errorf("expected 01:15 on the 11th, got %v", got.Start)
+	}
+}
@@ -0,0 +1,245 @@
+package validations
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var certBucket = []byte("certs")
+
+// CertEntry is a cached certificate chain.
+type CertEntry struct {
+	// Chain is the PEM encoded certificate chain.
+	Chain []byte `json:"chain"`
+	// FetchedAt is when the chain was downloaded.
+	FetchedAt time.Time `json:"fetchedAt"`
+	// NotAfter is when the signing certificate expires.
+	NotAfter time.Time `json:"notAfter"`
+}
+
+// CertCache stores certificate chains by their URL so they do not need to be
+// downloaded for every request. It must be safe for concurrent use.
+type CertCache interface {
+	// Get gets a certificate chain. If it is not cached, it returns nil and no
+	// error.
+	Get(chainURL string) (*CertEntry, error)
+	// Put stores a certificate chain.
+	Put(chainURL string, entry *CertEntry) error
+	// Delete removes a certificate chain.
+	Delete(chainURL string) error
+}
+
+// encodeEntry encodes a CertEntry for storage.
+func encodeEntry(entry *CertEntry) ([]byte, error) {
+	return json.Marshal(entry)
+}
+
+// decodeEntry decodes a stored CertEntry. Chains stored before entries were
+// used are plain PEM, so they are returned without any times.
+func decodeEntry(data []byte) (*CertEntry, error) {
+	if !bytes.HasPrefix(data, []byte("{")) {
+		return &CertEntry{Chain: data}, nil
+	}
+
+	var entry CertEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, err
+	}
+
+	return &entry, nil
+}
+
+// MemoryCertCache is a CertCache that keeps certificate chains in memory.
+type MemoryCertCache struct {
+	mu      sync.RWMutex
+	entries map[string]CertEntry
+}
+
+// NewMemoryCertCache creates a new MemoryCertCache.
+func NewMemoryCertCache() *MemoryCertCache {
+	return &MemoryCertCache{
+		entries: make(map[string]CertEntry),
+	}
+}
+
+// Get gets a certificate chain.
+func (c *MemoryCertCache) Get(chainURL string) (*CertEntry, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	entry, ok := c.entries[chainURL]
+	if !ok {
+		return nil, nil
+	}
+
+	return &entry, nil
+}
+
+// Put stores a certificate chain.
+func (c *MemoryCertCache) Put(chainURL string, entry *CertEntry) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[chainURL] = *entry
+
+	return nil
+}
+
+// Delete removes a certificate chain.
+func (c *MemoryCertCache) Delete(chainURL string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.entries, chainURL)
+
+	return nil
+}
+
+// BoltCertCache is a CertCache that stores certificate chains in a bolt
+// database.
+type BoltCertCache struct {
+	db *bolt.DB
+}
+
+// NewBoltCertCache creates a new BoltCertCache. The bucket is created when the
+// first chain is stored.
+func NewBoltCertCache(db *bolt.DB) *BoltCertCache {
+	return &BoltCertCache{db: db}
+}
+
+// Get gets a certificate chain.
+func (c *BoltCertCache) Get(chainURL string) (*CertEntry, error) {
+	var data []byte
+
+	err := c.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(certBucket)
+		if b == nil {
+			return nil
+		}
+
+		// The data is only valid during the transaction
+		if v := b.Get([]byte(chainURL)); v != nil {
+			data = append([]byte(nil), v...)
+		}
+
+		return nil
+	})
+	if err != nil || data == nil {
+		return nil, err
+	}
+
+	return decodeEntry(data)
+}
+
+// Put stores a certificate chain.
+func (c *BoltCertCache) Put(chainURL string, entry *CertEntry) error {
+	data, err := encodeEntry(entry)
+	if err != nil {
+		return err
+	}
+
+	return c.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists(certBucket)
+		if err != nil {
+			return err
+		}
+
+		return b.Put([]byte(chainURL), data)
+	})
+}
+
+// Delete removes a certificate chain.
+func (c *BoltCertCache) Delete(chainURL string) error {
+	return c.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(certBucket)
+		if b == nil {
+			return nil
+		}
+
+		return b.Delete([]byte(chainURL))
+	})
+}
+
+// Close closes the database.
+func (c *BoltCertCache) Close() error {
+	return c.db.Close()
+}
+
+// FileCertCache is a CertCache that stores each certificate chain as a file in
+// a directory.
+type FileCertCache struct {
+	dir string
+}
+
+// NewFileCertCache creates a new FileCertCache, creating the directory if
+// needed.
+func NewFileCertCache(dir string) (*FileCertCache, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+
+	return &FileCertCache{dir: dir}, nil
+}
+
+// path gets the file for a chain URL.
+func (c *FileCertCache) path(chainURL string) string {
+	sum := sha256.Sum256([]byte(chainURL))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+// Get gets a certificate chain.
+func (c *FileCertCache) Get(chainURL string) (*CertEntry, error) {
+	data, err := ioutil.ReadFile(c.path(chainURL))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return decodeEntry(data)
+}
+
+// Put stores a certificate chain. The file is replaced atomically so it is
+// never partially written.
+func (c *FileCertCache) Put(chainURL string, entry *CertEntry) error {
+	data, err := encodeEntry(entry)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(c.dir, ".chain-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), c.path(chainURL))
+}
+
+// Delete removes a certificate chain.
+func (c *FileCertCache) Delete(chainURL string) error {
+	err := os.Remove(c.path(chainURL))
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	return err
+}
@@ -0,0 +1,87 @@
+package validations
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestCertCaches(t *testing.T) {
+	dir := t.TempDir()
+
+	db, err := bolt.Open(filepath.Join(dir, "certs.db"), 0600, nil)
+	if err != nil {
+		t.Fatalf("unable to open database: %v", err)
+	}
+	defer db.Close()
+
+	fileCache, err := NewFileCertCache(filepath.Join(dir, "certs"))
+	if err != nil {
+		t.Fatalf("unable to create file cache: %v", err)
+	}
+
+	const chainURL = "https://s3.amazonaws.com/echo.api/echo-api-cert.pem"
+	entry := &CertEntry{
+		Chain:     []byte("-----BEGIN CERTIFICATE-----"),
+		FetchedAt: time.Now().UTC().Truncate(time.Second),
+		NotAfter:  time.Now().UTC().Add(time.Hour).Truncate(time.Second),
+	}
+
+	for name, cache := range map[string]CertCache{
+		"memory": NewMemoryCertCache(),
+		"bolt":   NewBoltCertCache(db),
+		"file":   fileCache,
+	} {
+		got, err := cache.Get(chainURL)
+		if err != nil || got != nil {
+			t.Errorf("%s: expected nothing cached, got %+v, %v", name, got, err)
+		}
+
+		if err = cache.Put(chainURL, entry); err != nil {
+			t.Errorf("%s: unable to put: %v", name, err)
+		}
+
+		got, err = cache.Get(chainURL)
+		if err != nil || !reflect.DeepEqual(got, entry) {
+			t.Errorf("%s: expected cached entry, got %+v, %v", name, got, err)
+		}
+
+		if err = cache.Delete(chainURL); err != nil {
+			t.Errorf("%s: unable to delete: %v", name, err)
+		}
+
+		got, err = cache.Get(chainURL)
+		if err != nil || got != nil {
+			t.Errorf("%s: expected deleted entry, got %+v, %v", name, got, err)
+		}
+	}
+}
+
+func TestLegacyBoltEntry(t *testing.T) {
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "certs.db"), 0600, nil)
+	if err != nil {
+		t.Fatalf("unable to open database: %v", err)
+	}
+	defer db.Close()
+
+	// Chains used to be stored as plain PEM
+	chain := []byte("-----BEGIN CERTIFICATE-----")
+	err = db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists(certBucket)
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte("url"), chain)
+	})
+	if err != nil {
+		t.Fatalf("unable to store chain: %v", err)
+	}
+
+	entry, err := NewBoltCertCache(db).Get("url")
+	if err != nil || entry == nil || string(entry.Chain) != string(chain) {
+		t.Errorf("expected legacy chain, got %+v, %v", entry, err)
+	}
+}
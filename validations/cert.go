@@ -2,83 +2,175 @@ package validations
 
 import (
 	"bytes"
-	"errors"
-	"log"
+	"io"
+	"path"
 	"strings"
+	"time"
 
 	"net/http"
 	"net/url"
 
 	"crypto/x509"
 	"encoding/pem"
-
-	"github.com/boltdb/bolt"
 )
 
-var certBucket = []byte("certs")
+// signingName is the name the signing certificate must be valid for.
+const signingName = "echo-api.amazon.com"
 
-var (
-	errNoChain         = errors.New("unable to find certificate chain header")
-	errUnacceptableURL = errors.New("url provided is not acceptable")
-)
+// MaxChainSize is the largest certificate chain that will be downloaded, in
+// bytes.
+const MaxChainSize = 64 * 1024
+
+// DefaultDownloadTimeout is how long a certificate chain download may take
+// when the Validator has no Client.
+const DefaultDownloadTimeout = 10 * time.Second
 
-// hasCheckedDB prevents us having to always call initDB.
-var hasCheckedDB = false
+// defaultClient is used to download certificate chains when the Validator has
+// no Client.
+var defaultClient = &http.Client{
+	Timeout: DefaultDownloadTimeout,
+}
 
 // ValidateCertificate ensures that a request was from Amazon.
-// If DB is not nil, it caches certificate chains to prevent unneeded downloads
-// of the same certificate multiple times. It then returns the certificate so
-// it can be used to verify the signature later.
+// If DB is not nil, it caches certificate chains in it, otherwise they are
+// cached in memory, to prevent unneeded downloads of the same certificate
+// multiple times. It then returns the certificate so it can be used to verify
+// the signature later.
 func ValidateCertificate(r *http.Request) (*x509.Certificate, error) {
+	return standard().ValidateCertificate(r)
+}
+
+// ValidateCertificate ensures that a request was from Amazon, using the
+// Validator's Cache for certificate chains. Cached chains that fail to verify
+// are downloaded again, and chains nearing expiry are refreshed.
+func (v *Validator) ValidateCertificate(r *http.Request) (*x509.Certificate, error) {
 	// First, we need to extract the chain URL from the request
 	chainURL, err := getChainURL(r)
 	if err != nil {
 		return nil, err
 	}
 
-	// Next, we need to make sure it's a valid URL
-	err = verifyChainURL(chainURL)
+	// Next, we need to make sure it's a valid URL. The normalized URL is
+	// used from here on so equivalent URLs share a cache entry.
+	chainURL, err = normalizeChainURL(chainURL)
 	if err != nil {
 		return nil, err
 	}
 
-	// If we haven't checked the DB yet, do that
-	if !hasCheckedDB {
-		if DB != nil {
-			if err = initDB(); err != nil {
-				return nil, err
-			}
-		} else {
-			log.Println("No database is currently configured, it is highly suggested to do so")
-		}
+	now := v.now()
 
-		hasCheckedDB = true
+	// If we already verified this certificate, there's nothing else to do
+	if cert := v.verified.get(chainURL, now); cert != nil {
+		return cert, nil
 	}
 
-	var certChain []byte
-
-	// If we have a DB, see if we have the cert already cached
-	if DB != nil {
-		certChain = getCertFromCache(chainURL)
+	// See if we have a good chain cached
+	cert, entry, err := v.cachedCert(chainURL, now)
+	if err != nil {
+		return nil, err
 	}
 
-	// If we don't or it's zero length, try and load it
-	if certChain == nil || len(certChain) == 0 {
-		certChain, err = loadCertChain(chainURL)
-		if err != nil {
+	// If we don't or it's nearly expired, try and load it. Concurrent
+	// requests for the same chain share a single download.
+	if cert == nil || v.shouldRefresh(cert, entry, now) {
+		fresh, err := v.fetches.do(chainURL, func() (*x509.Certificate, error) {
+			return v.fetchCert(chainURL, now)
+		})
+		if err != nil && cert == nil {
 			return nil, err
 		}
+
+		// If a refresh failed, keep using the cached one until it expires
+		if err == nil {
+			cert = fresh
+		}
+	}
+
+	// Remember it so we don't have to verify it again
+	v.verified.put(chainURL, cert, v.refreshAt(cert, now))
+
+	return cert, nil
+}
+
+// cachedCert gets and verifies a cached certificate chain. If the chain fails
+// to verify, it is removed from the cache and no certificate is returned.
+func (v *Validator) cachedCert(chainURL string, now time.Time) (*x509.Certificate, *CertEntry, error) {
+	if v.Cache == nil {
+		return nil, nil, nil
+	}
+
+	entry, err := v.Cache.Get(chainURL)
+	if err != nil || entry == nil || len(entry.Chain) == 0 {
+		return nil, nil, err
 	}
 
-	// If we have a DB, cache it so we don't have to load it again
-	if DB != nil {
-		if err = saveCertToCache(chainURL, certChain); err != nil {
+	// A bad chain is replaced once it is downloaded again
+	cert, err := v.verifyCert(entry.Chain, now)
+	if err != nil {
+		v.Cache.Delete(chainURL)
+		return nil, nil, nil
+	}
+
+	return cert, entry, nil
+}
+
+// fetchCert downloads and verifies a certificate chain, then caches it.
+func (v *Validator) fetchCert(chainURL string, now time.Time) (*x509.Certificate, error) {
+	client := v.Client
+	if client == nil {
+		client = defaultClient
+	}
+
+	certChain, err := loadCertChain(client, chainURL)
+	if err != nil {
+		return nil, err
+	}
+
+	cert, err := v.verifyCert(certChain, now)
+	if err != nil {
+		return nil, err
+	}
+
+	if v.Cache != nil {
+		entry := &CertEntry{
+			Chain:     certChain,
+			FetchedAt: now,
+			NotAfter:  cert.NotAfter,
+		}
+
+		if err = v.Cache.Put(chainURL, entry); err != nil {
 			return nil, err
 		}
 	}
 
-	// Return our signing certificate after verifying it
-	return verifyCert(certChain)
+	return cert, nil
+}
+
+// shouldRefresh is if a cached certificate is within RefreshBefore of
+// expiring and was not downloaded too recently to try again.
+func (v *Validator) shouldRefresh(cert *x509.Certificate, entry *CertEntry, now time.Time) bool {
+	if v.RefreshBefore <= 0 || now.Before(cert.NotAfter.Add(-v.RefreshBefore)) {
+		return false
+	}
+
+	return now.Sub(entry.FetchedAt) >= refreshRetry
+}
+
+// refreshAt is when a verified certificate should next be checked for a
+// refresh.
+func (v *Validator) refreshAt(cert *x509.Certificate, now time.Time) time.Time {
+	at := cert.NotAfter.Add(-v.RefreshBefore)
+	if v.RefreshBefore <= 0 || at.After(now) {
+		return at
+	}
+
+	// Already nearly expired, so check again after a while
+	at = now.Add(refreshRetry)
+	if at.After(cert.NotAfter) {
+		return cert.NotAfter
+	}
+
+	return at
 }
 
 // getChainURL attempts to get the certificate chain URL
@@ -86,96 +178,136 @@ func ValidateCertificate(r *http.Request) (*x509.Certificate, error) {
 func getChainURL(r *http.Request) (string, error) {
 	chainURL := r.Header.Get("SignatureCertChainUrl")
 	if chainURL == "" {
-		return "", errNoChain
+		return "", newError(StageChainURL, ErrNoChain, "")
 	}
 	return chainURL, nil
 }
 
-// verifyChainURL verifies that the URL provided is
-// acceptable for provided requirements.
-func verifyChainURL(chainURL string) error {
+// normalizeChainURL verifies that the URL provided is acceptable for Amazon's
+// requirements and returns it normalized. The scheme must be https and the
+// host s3.amazonaws.com, both case-insensitive, on port 443 if there is one.
+// The path is normalized before it must start with /echo.api/, which is
+// case-sensitive.
+func normalizeChainURL(chainURL string) (string, error) {
 	u, err := url.Parse(chainURL)
 	if err != nil {
-		return err
+		return "", newError(StageChainURL, ErrUnacceptableURL, err.Error())
+	}
+
+	if !strings.EqualFold(u.Scheme, "https") || u.User != nil || u.Opaque != "" {
+		return "", newError(StageChainURL, ErrUnacceptableURL, chainURL)
+	}
+
+	if !strings.EqualFold(u.Hostname(), "s3.amazonaws.com") {
+		return "", newError(StageChainURL, ErrUnacceptableURL, chainURL)
+	}
+
+	if port := u.Port(); port != "" && port != "443" {
+		return "", newError(StageChainURL, ErrUnacceptableURL, chainURL)
 	}
 
-	if u.Scheme != "https" || u.Host != "s3.amazonaws.com" || !strings.HasPrefix(u.Path, "/echo.api/") {
-		return errUnacceptableURL
+	// Resolve any dot segments before checking the prefix
+	cleaned := path.Clean(u.Path)
+	if !strings.HasPrefix(cleaned, "/echo.api/") {
+		return "", newError(StageChainURL, ErrUnacceptableURL, chainURL)
 	}
 
-	return nil
+	normalized := url.URL{
+		Scheme:   "https",
+		Host:     "s3.amazonaws.com",
+		Path:     cleaned,
+		RawQuery: u.RawQuery,
+	}
+
+	return normalized.String(), nil
 }
 
 // loadCertChain attempts to load the certificate chain into a byte array.
-func loadCertChain(chainURL string) ([]byte, error) {
-	resp, err := http.Get(chainURL)
+// Chains larger than MaxChainSize are rejected.
+func loadCertChain(client *http.Client, chainURL string) ([]byte, error) {
+	resp, err := client.Get(chainURL)
 	if err != nil {
-		return nil, err
+		return nil, newError(StageDownload, ErrDownloadFailed, err.Error())
 	}
 	defer resp.Body.Close()
 
-	buf := bytes.NewBuffer(make([]byte, 0, resp.ContentLength))
-	_, err = buf.ReadFrom(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return nil, newError(StageDownload, ErrDownloadFailed, resp.Status)
+	}
+
+	if resp.ContentLength > MaxChainSize {
+		return nil, newError(StageDownload, ErrChainTooLarge, "")
+	}
+
+	var buf bytes.Buffer
+	if resp.ContentLength > 0 {
+		buf.Grow(int(resp.ContentLength))
+	}
+
+	if _, err = buf.ReadFrom(io.LimitReader(resp.Body, MaxChainSize+1)); err != nil {
+		return nil, newError(StageDownload, ErrDownloadFailed, err.Error())
+	}
 
-	return buf.Bytes(), err
+	if buf.Len() > MaxChainSize {
+		return nil, newError(StageDownload, ErrChainTooLarge, "")
+	}
+
+	return buf.Bytes(), nil
 }
 
 // verifyCert verifies that the signing certificate is part of the chain,
-// is valid for the name provided, and has not expired.
-func verifyCert(certChain []byte) (*x509.Certificate, error) {
-	// We need to use their root certificate chain
-	roots := x509.NewCertPool()
-
+// is valid for the name provided, and has not expired. The chain must lead to
+// one of the Validator's Roots, or the system's if it has none, so a chain
+// that includes its own root is not trusted.
+func (v *Validator) verifyCert(certChain []byte, now time.Time) (*x509.Certificate, error) {
 	// First certificate in file is always signing cert
 	signCert, remaining := pem.Decode(certChain)
-
-	// Everything else is part of the root chain
-	roots.AppendCertsFromPEM(remaining)
+	if signCert == nil || signCert.Type != "CERTIFICATE" {
+		return nil, newError(StageCertificate, ErrNoCertificate, "")
+	}
 
 	cert, err := x509.ParseCertificate(signCert.Bytes)
 	if err != nil {
-		return nil, err
+		return nil, newError(StageCertificate, err, "")
+	}
+
+	// It must be valid right now
+	if now.Before(cert.NotBefore) || now.After(cert.NotAfter) {
+		return nil, newError(StageCertificate, ErrCertExpired, cert.NotAfter.Format(time.RFC3339))
+	}
+
+	// It must be for Amazon's name
+	if !hasDNSName(cert, signingName) {
+		return nil, newError(StageCertificate, ErrWrongName, strings.Join(cert.DNSNames, ","))
 	}
 
+	// Everything else is an intermediate that may lead to a root
+	intermediates := x509.NewCertPool()
+	intermediates.AppendCertsFromPEM(remaining)
+
 	// We need to verify the certificate is valid for this name
 	opts := x509.VerifyOptions{
-		DNSName: "echo-api.amazon.com",
-		Roots:   roots,
+		DNSName:       signingName,
+		Roots:         v.Roots,
+		Intermediates: intermediates,
+		CurrentTime:   now,
 	}
 
 	// Actually verify the chain
-	_, err = cert.Verify(opts)
-
-	// Return if there was any error and the certificate for later uses
-	return cert, err
-}
+	if _, err = cert.Verify(opts); err != nil {
+		return nil, newError(StageCertificate, ErrUntrustedChain, err.Error())
+	}
 
-// initDB attempts to create the certificates bucket.
-func initDB() error {
-	return DB.Update(func(tx *bolt.Tx) error {
-		_, err := tx.CreateBucketIfNotExists(certBucket)
-		return err
-	})
+	return cert, nil
 }
 
-// getCertFromCache attempts to get the certificate from a bolt database.
-func getCertFromCache(chainURL string) []byte {
-	var data []byte
-
-	DB.View(func(tx *bolt.Tx) error {
-		b := tx.Bucket(certBucket)
-		data = b.Get([]byte(chainURL))
-
-		return nil
-	})
-
-	return data
-}
+// hasDNSName is if a certificate's Subject Alternative Names include a name.
+func hasDNSName(cert *x509.Certificate, name string) bool {
+	for _, dnsName := range cert.DNSNames {
+		if strings.EqualFold(dnsName, name) {
+			return true
+		}
+	}
 
-// saveCertToCache puts a certificate into the cache.
-func saveCertToCache(chainURL string, cert []byte) error {
-	return DB.Update(func(tx *bolt.Tx) error {
-		b := tx.Bucket(certBucket)
-		return b.Put([]byte(chainURL), cert)
-	})
+	return false
 }
@@ -0,0 +1,254 @@
+package validations
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestValidateCertificateRefetchesBadChain(t *testing.T) {
+	expired := newTestChain(t, time.Now().Add(-time.Minute))
+	fresh := newTestChain(t, time.Now().Add(48*time.Hour))
+
+	v := newTestValidator(t, expired)
+
+	var downloads int32
+	v.Client = chainClient(fresh.pem, &downloads)
+
+	cert, err := v.ValidateCertificate(fresh.request(t, []byte("{}")))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !cert.Equal(fresh.cert) {
+		t.Error("expected the downloaded certificate")
+	}
+
+	entry, err := v.Cache.Get(testChainURL)
+	if err != nil || entry == nil || !bytes.Equal(entry.Chain, fresh.pem) {
+		t.Errorf("expected the downloaded chain to be cached, got %+v, %v", entry, err)
+	}
+
+	if downloads != 1 {
+		t.Errorf("expected 1 download, got %d", downloads)
+	}
+}
+
+func TestValidateCertificateRefreshesNearExpiry(t *testing.T) {
+	old := newTestChain(t, time.Now().Add(time.Hour))
+	fresh := newTestChain(t, time.Now().Add(48*time.Hour))
+
+	v := newTestValidator(t, old)
+
+	// The cached chain was fetched long enough ago to try again
+	entry, _ := v.Cache.Get(testChainURL)
+	entry.FetchedAt = time.Now().Add(-2 * refreshRetry)
+	v.Cache.Put(testChainURL, entry)
+
+	var downloads int32
+	v.Client = chainClient(fresh.pem, &downloads)
+
+	for i := 0; i < 3; i++ {
+		cert, err := v.ValidateCertificate(fresh.request(t, []byte("{}")))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if !cert.Equal(fresh.cert) {
+			t.Error("expected the refreshed certificate")
+		}
+	}
+
+	if downloads != 1 {
+		t.Errorf("expected 1 download, got %d", downloads)
+	}
+}
+
+func TestValidateCertificateKeepsChainWhenRefreshFails(t *testing.T) {
+	old := newTestChain(t, time.Now().Add(time.Hour))
+	v := newTestValidator(t, old)
+
+	entry, _ := v.Cache.Get(testChainURL)
+	entry.FetchedAt = time.Now().Add(-2 * refreshRetry)
+	v.Cache.Put(testChainURL, entry)
+
+	cert, err := v.ValidateCertificate(old.request(t, []byte("{}")))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !cert.Equal(old.cert) {
+		t.Error("expected the cached certificate")
+	}
+}
+
+func TestValidateCertificateDeduplicatesDownloads(t *testing.T) {
+	chain := newTestChain(t, time.Now().Add(48*time.Hour))
+
+	v := NewValidator("app")
+	v.Roots = testRoots(t)
+
+	var downloads int32
+	release := make(chan struct{})
+
+	client := chainClient(chain.pem, &downloads)
+	transport := client.Transport
+	client.Transport = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		<-release
+		return transport.RoundTrip(r)
+	})
+	v.Client = client
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+
+	for i := 0; i < cap(errs); i++ {
+		r := chain.request(t, []byte("{}"))
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			_, err := v.ValidateCertificate(r)
+			errs <- err
+		}()
+	}
+
+	// Give every request a chance to miss the cache before the download ends
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+
+	if downloads != 1 {
+		t.Errorf("expected 1 download, got %d", downloads)
+	}
+}
+
+func TestLoadCertChainLimitsSize(t *testing.T) {
+	var downloads int32
+	client := chainClient(bytes.Repeat([]byte("a"), MaxChainSize+1), &downloads)
+
+	if _, err := loadCertChain(client, testChainURL); !errors.Is(err, ErrChainTooLarge) {
+		t.Errorf("expected %v, got %v", ErrChainTooLarge, err)
+	}
+
+	// Servers may not send a length, so the body itself must be limited too
+	transport := client.Transport
+	client.Transport = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		resp, err := transport.RoundTrip(r)
+		resp.ContentLength = -1
+		return resp, err
+	})
+
+	if _, err := loadCertChain(client, testChainURL); !errors.Is(err, ErrChainTooLarge) {
+		t.Errorf("expected %v without a length, got %v", ErrChainTooLarge, err)
+	}
+}
+
+func TestNormalizeChainURL(t *testing.T) {
+	const normalized = "https://s3.amazonaws.com/echo.api/echo-api-cert.pem"
+
+	for _, tt := range []struct {
+		url  string
+		want string
+	}{
+		// Amazon's examples of valid URLs
+		{"https://s3.amazonaws.com/echo.api/echo-api-cert.pem", normalized},
+		{"https://s3.amazonaws.com:443/echo.api/echo-api-cert.pem", normalized},
+		{"https://s3.amazonaws.com/echo.api/../echo.api/echo-api-cert.pem", normalized},
+
+		// Scheme and host are case-insensitive
+		{"HTTPS://s3.amazonaws.com/echo.api/echo-api-cert.pem", normalized},
+		{"https://S3.AmAzOnAwS.CoM/echo.api/echo-api-cert.pem", normalized},
+		{"https://s3.amazonaws.com/echo.api/./echo-api-cert.pem", normalized},
+
+		// Amazon's examples of invalid URLs
+		{"http://s3.amazonaws.com/echo.api/echo-api-cert.pem", ""},
+		{"https://notamazon.com/echo.api/echo-api-cert.pem", ""},
+		{"https://s3.amazonaws.com/EcHo.aPi/echo-api-cert.pem", ""},
+		{"https://s3.amazonaws.com/invalid.path/echo-api-cert.pem", ""},
+		{"https://s3.amazonaws.com:563/echo.api/echo-api-cert.pem", ""},
+
+		// Paths must be normalized before the prefix is checked
+		{"https://s3.amazonaws.com/echo.api/../evil/echo-api-cert.pem", ""},
+		{"https://s3.amazonaws.com/echo.api/%2e%2e/evil/echo-api-cert.pem", ""},
+		{"https://s3.amazonaws.com/echo.api", ""},
+		{"https://s3.amazonaws.com/echo.api/", ""},
+
+		// Anything else unusual
+		{"https://s3.amazonaws.com.evil.com/echo.api/echo-api-cert.pem", ""},
+		{"https://user@s3.amazonaws.com/echo.api/echo-api-cert.pem", ""},
+		{"https://s3.amazonaws.com:/echo.api/echo-api-cert.pem", normalized},
+		{"s3.amazonaws.com/echo.api/echo-api-cert.pem", ""},
+		{"", ""},
+	} {
+		got, err := normalizeChainURL(tt.url)
+		if tt.want == "" {
+			if err == nil {
+				t.Errorf("%q: expected an error, got %q", tt.url, got)
+			}
+			continue
+		}
+
+		if err != nil || got != tt.want {
+			t.Errorf("%q: expected %q, got %q, %v", tt.url, tt.want, got, err)
+		}
+	}
+}
+
+func TestVerifyCert(t *testing.T) {
+	now := time.Now()
+
+	v := NewValidator("app")
+	v.Roots = testRoots(t)
+
+	good := newTestChain(t, now.Add(48*time.Hour))
+	untrusted := newTestChainFrom(t, newTestCA(t), []string{signingName}, now.Add(48*time.Hour))
+	wrongName := newTestChainFrom(t, sharedCA, []string{"example.com"}, now.Add(48*time.Hour))
+
+	for _, tt := range []struct {
+		name  string
+		chain []byte
+		now   time.Time
+		err   bool
+	}{
+		{"good", good.pem, now, false},
+		{"self-signed root", untrusted.pem, now, true},
+		{"wrong name", wrongName.pem, now, true},
+		{"not yet valid", good.pem, good.cert.NotBefore.Add(-time.Second), true},
+		{"expired", good.pem, good.cert.NotAfter.Add(time.Second), true},
+		{"no certificate", []byte("not a certificate"), now, true},
+		{"empty", nil, now, true},
+	} {
+		cert, err := v.verifyCert(tt.chain, tt.now)
+		if tt.err && err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		} else if !tt.err && (err != nil || cert == nil) {
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+		}
+	}
+}
+
+func TestValidatorClock(t *testing.T) {
+	chain := newTestChain(t, time.Now().Add(48*time.Hour))
+
+	v := newTestValidator(t, chain)
+	v.Clock = func() time.Time {
+		return chain.cert.NotAfter.Add(time.Minute)
+	}
+
+	if _, err := v.ValidateCertificate(chain.request(t, []byte("{}"))); err == nil {
+		t.Error("expected an error for a certificate expired by the clock")
+	}
+}
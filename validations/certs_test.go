@@ -0,0 +1,240 @@
+package validations
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const testChainURL = "https://s3.amazonaws.com/echo.api/echo-api-cert.pem"
+
+// testCA is a certificate authority for test chains.
+type testCA struct {
+	key  *rsa.PrivateKey
+	cert *x509.Certificate
+	der  []byte
+}
+
+var (
+	sharedCAOnce sync.Once
+	sharedCA     *testCA
+)
+
+// newTestCA creates a self-signed certificate authority.
+func newTestCA(tb testing.TB) *testCA {
+	tb.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		tb.Fatalf("unable to generate root key: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test Root"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(10 * 365 * 24 * time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		tb.Fatalf("unable to create root: %v", err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		tb.Fatalf("unable to parse root: %v", err)
+	}
+
+	return &testCA{
+		key:  key,
+		cert: cert,
+		der:  der,
+	}
+}
+
+// testRoots gets a pool with the root trusted by test Validators.
+func testRoots(tb testing.TB) *x509.CertPool {
+	tb.Helper()
+
+	sharedCAOnce.Do(func() {
+		sharedCA = newTestCA(tb)
+	})
+
+	roots := x509.NewCertPool()
+	roots.AddCert(sharedCA.cert)
+
+	return roots
+}
+
+// testChain is a signing certificate, its key, and the PEM chain for it.
+type testChain struct {
+	key  *rsa.PrivateKey
+	cert *x509.Certificate
+	pem  []byte
+}
+
+// newTestChain creates a signing certificate for echo-api.amazon.com valid
+// until notAfter, issued by the root trusted by test Validators.
+func newTestChain(tb testing.TB, notAfter time.Time) *testChain {
+	tb.Helper()
+
+	testRoots(tb)
+
+	return newTestChainFrom(tb, sharedCA, []string{signingName}, notAfter)
+}
+
+// newTestChainFrom creates a signing certificate for names valid until
+// notAfter, followed by the root that issued it.
+func newTestChainFrom(tb testing.TB, ca *testCA, names []string, notAfter time.Time) *testChain {
+	tb.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		tb.Fatalf("unable to generate key: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: signingName},
+		DNSNames:     names,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     notAfter,
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		tb.Fatalf("unable to create certificate: %v", err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		tb.Fatalf("unable to parse certificate: %v", err)
+	}
+
+	var chain bytes.Buffer
+	pem.Encode(&chain, &pem.Block{Type: "CERTIFICATE", Bytes: der})
+	pem.Encode(&chain, &pem.Block{Type: "CERTIFICATE", Bytes: ca.der})
+
+	return &testChain{
+		key:  key,
+		cert: cert,
+		pem:  chain.Bytes(),
+	}
+}
+
+// sign signs a body with the SHA-256 signature Alexa uses.
+func (c *testChain) sign(tb testing.TB, body []byte) string {
+	tb.Helper()
+
+	sum := sha256.Sum256(body)
+
+	sig, err := rsa.SignPKCS1v15(rand.Reader, c.key, crypto.SHA256, sum[:])
+	if err != nil {
+		tb.Fatalf("unable to sign: %v", err)
+	}
+
+	return base64.StdEncoding.EncodeToString(sig)
+}
+
+// signSHA1 signs a body with the legacy SHA-1 signature.
+func (c *testChain) signSHA1(tb testing.TB, body []byte) string {
+	tb.Helper()
+
+	sum := sha1.Sum(body)
+
+	sig, err := rsa.SignPKCS1v15(rand.Reader, c.key, crypto.SHA1, sum[:])
+	if err != nil {
+		tb.Fatalf("unable to sign: %v", err)
+	}
+
+	return base64.StdEncoding.EncodeToString(sig)
+}
+
+// request creates a request for a body with a SHA-256 signature.
+func (c *testChain) request(tb testing.TB, body []byte) *http.Request {
+	tb.Helper()
+
+	return newTestRequest(body, c.sign(tb, body))
+}
+
+// newTestRequest creates a request for a body with a SHA-256 signature.
+func newTestRequest(body []byte, sig string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/alexa", bytes.NewReader(body))
+	r.Header.Set("SignatureCertChainUrl", testChainURL)
+	r.Header.Set("Signature-256", sig)
+
+	return r
+}
+
+// newTestValidator creates a Validator with the chain already cached. Any
+// download fails.
+func newTestValidator(tb testing.TB, chain *testChain) *Validator {
+	tb.Helper()
+
+	v := NewValidator("app")
+	v.Roots = testRoots(tb)
+	v.Client = &http.Client{
+		Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+			return nil, errors.New("unexpected download of " + r.URL.String())
+		}),
+	}
+
+	entry := &CertEntry{
+		Chain:     chain.pem,
+		FetchedAt: time.Now(),
+		NotAfter:  chain.cert.NotAfter,
+	}
+
+	if err := v.Cache.Put(testChainURL, entry); err != nil {
+		tb.Fatalf("unable to cache chain: %v", err)
+	}
+
+	return v
+}
+
+// roundTripFunc is an http.RoundTripper that calls a func.
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+// chainClient creates a Client that responds to every request with a chain,
+// counting each download.
+func chainClient(chain []byte, downloads *int32) *http.Client {
+	return &http.Client{
+		Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+			atomic.AddInt32(downloads, 1)
+
+			return &http.Response{
+				StatusCode:    http.StatusOK,
+				Status:        http.StatusText(http.StatusOK),
+				ContentLength: int64(len(chain)),
+				Body:          ioutil.NopCloser(bytes.NewReader(chain)),
+				Request:       r,
+			}, nil
+		}),
+	}
+}
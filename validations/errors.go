@@ -0,0 +1,97 @@
+package validations
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Stage is the part of validating a request that failed.
+type Stage string
+
+// Stages of validating a request, in the order they are checked.
+const (
+	// StageChainURL is finding and checking the certificate chain URL.
+	StageChainURL Stage = "chain_url"
+	// StageDownload is downloading the certificate chain.
+	StageDownload Stage = "download"
+	// StageCertificate is verifying the signing certificate.
+	StageCertificate Stage = "certificate"
+	// StageSignature is verifying the signature of the body.
+	StageSignature Stage = "signature"
+	// StageBody is reading and parsing the body.
+	StageBody Stage = "body"
+	// StageTimestamp is checking the timestamp is within TimeLimit.
+	StageTimestamp Stage = "timestamp"
+	// StageApplication is checking the request is for the right application.
+	StageApplication Stage = "application"
+	// StageReplay is checking the request has not been used before.
+	StageReplay Stage = "replay"
+)
+
+var (
+	// ErrNoChain means the request had no certificate chain URL.
+	ErrNoChain = errors.New("unable to find certificate chain header")
+	// ErrUnacceptableURL means the certificate chain URL was not Amazon's.
+	ErrUnacceptableURL = errors.New("url provided is not acceptable")
+	// ErrDownloadFailed means the certificate chain could not be downloaded,
+	// such as for a timeout or a status other than 200 OK.
+	ErrDownloadFailed = errors.New("unable to download certificate chain")
+	// ErrChainTooLarge means the certificate chain was over MaxChainSize.
+	ErrChainTooLarge = errors.New("certificate chain is too large")
+	// ErrNoCertificate means there was no certificate in the chain.
+	ErrNoCertificate = errors.New("no certificate was found in the chain")
+	// ErrCertExpired means the signing certificate was expired or not yet
+	// valid.
+	ErrCertExpired = errors.New("signing certificate is not valid at this time")
+	// ErrWrongName means the signing certificate was not for Amazon.
+	ErrWrongName = errors.New("signing certificate is not valid for " + signingName)
+	// ErrUntrustedChain means the signing certificate did not chain to a
+	// trusted root.
+	ErrUntrustedChain = errors.New("certificate chain is not trusted")
+	// ErrNoSignature means the request had no usable signature.
+	ErrNoSignature = errors.New("unable to find signature header")
+	// ErrBadSignature means the signature did not match the body.
+	ErrBadSignature = errors.New("signature does not match the body")
+	// ErrOutsideTime means the timestamp was not within TimeLimit.
+	ErrOutsideTime = errors.New("timestamp difference was greater than allowed")
+	// ErrWrongApp means the request was for another application.
+	ErrWrongApp = errors.New("application IDs do not match")
+	// ErrReplayed means a request with the same ID was already validated.
+	ErrReplayed = errors.New("request has already been used")
+)
+
+// ValidationError is why a request failed validation. Err is one of the
+// exported errors of this package, or the underlying error if there is none
+// for it, so it can be checked with errors.Is and errors.As.
+type ValidationError struct {
+	// Stage is the part of validation that failed.
+	Stage Stage
+	// Err is the reason it failed.
+	Err error
+	// Detail is any more information about the failure, such as the value
+	// that was rejected.
+	Detail string
+}
+
+// Error describes the stage and reason the request failed.
+func (e *ValidationError) Error() string {
+	if e.Detail == "" {
+		return fmt.Sprintf("validations: %s: %v", e.Stage, e.Err)
+	}
+
+	return fmt.Sprintf("validations: %s: %v: %s", e.Stage, e.Err, e.Detail)
+}
+
+// Unwrap gets the reason the request failed.
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// newError creates a ValidationError for a stage.
+func newError(stage Stage, err error, detail string) *ValidationError {
+	return &ValidationError{
+		Stage:  stage,
+		Err:    err,
+		Detail: detail,
+	}
+}
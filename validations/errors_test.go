@@ -0,0 +1,99 @@
+package validations
+
+import (
+	"crypto/x509"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-alexa/alexa/parser"
+)
+
+func TestValidationErrors(t *testing.T) {
+	chain := newTestChain(t, time.Now().Add(48*time.Hour))
+	v := newTestValidator(t, chain)
+
+	noChain := httptest.NewRequest(http.MethodPost, "/alexa", nil)
+
+	badURL := chain.request(t, []byte("{}"))
+	badURL.Header.Set("SignatureCertChainUrl", "https://s3.amazonaws.com/evil/cert.pem")
+
+	badSig := newTestRequest([]byte("{}"), chain.sign(t, []byte("[]")))
+
+	noSig := chain.request(t, []byte("{}"))
+	noSig.Header.Del("Signature-256")
+
+	// The test Validator's client fails every download
+	notCached := newTestValidator(t, chain)
+	notCached.Cache = NewMemoryCertCache()
+
+	// A chain that fails to verify is downloaded again before giving up
+	var downloads int32
+	untrusted := newTestValidator(t, chain)
+	untrusted.Roots = x509.NewCertPool()
+	untrusted.Client = newCertServer(t, chain.pem, &downloads)
+
+	ts := parser.Time(time.Now().Add(-time.Hour))
+	old := &parser.Event{Request: parser.Request{Timestamp: &ts}}
+
+	for _, tt := range []struct {
+		name  string
+		err   error
+		stage Stage
+		is    error
+	}{
+		{"no chain", validateCert(v, noChain), StageChainURL, ErrNoChain},
+		{"bad url", validateCert(v, badURL), StageChainURL, ErrUnacceptableURL},
+		{"download failed", validateCert(notCached, chain.request(t, []byte("{}"))), StageDownload, ErrDownloadFailed},
+		{"untrusted chain", validateCert(untrusted, chain.request(t, []byte("{}"))), StageCertificate, ErrUntrustedChain},
+		{"bad signature", validateSig(v, badSig, chain), StageSignature, ErrBadSignature},
+		{"no signature", validateSig(v, noSig, chain), StageSignature, ErrNoSignature},
+		{"old", v.ValidateRequest(old), StageTimestamp, ErrOutsideTime},
+		{"no timestamp", v.ValidateRequest(&parser.Event{}), StageTimestamp, ErrOutsideTime},
+	} {
+		var verr *ValidationError
+		if !errors.As(tt.err, &verr) {
+			t.Errorf("%s: expected a *ValidationError, got %v", tt.name, tt.err)
+			continue
+		}
+
+		if verr.Stage != tt.stage {
+			t.Errorf("%s: expected stage %s, got %s", tt.name, tt.stage, verr.Stage)
+		}
+
+		if !errors.Is(tt.err, tt.is) {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.is, tt.err)
+		}
+	}
+}
+
+func TestValidationErrorWrongApp(t *testing.T) {
+	ts := parser.Time(time.Now())
+	ev := &parser.Event{
+		Session: parser.Session{Application: parser.Application{ID: "other"}},
+		Request: parser.Request{Timestamp: &ts},
+	}
+
+	err := NewValidator("app").ValidateRequest(ev)
+	if !errors.Is(err, ErrWrongApp) {
+		t.Fatalf("expected %v, got %v", ErrWrongApp, err)
+	}
+
+	if want := "validations: application: application IDs do not match: other"; err.Error() != want {
+		t.Errorf("expected %q, got %q", want, err.Error())
+	}
+}
+
+// validateCert gets the error from validating a request's certificate.
+func validateCert(v *Validator, r *http.Request) error {
+	_, err := v.ValidateCertificate(r)
+	return err
+}
+
+// validateSig gets the error from validating a request's signature.
+func validateSig(v *Validator, r *http.Request, chain *testChain) error {
+	_, err := v.ValidateSignature(r, chain.cert)
+	return err
+}
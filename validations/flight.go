@@ -0,0 +1,53 @@
+package validations
+
+import (
+	"crypto/x509"
+	"sync"
+)
+
+// flightGroup coalesces concurrent calls for the same key, so a certificate
+// chain is only downloaded once no matter how many requests need it.
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall
+}
+
+// flightCall is a call in progress or completed.
+type flightCall struct {
+	wg   sync.WaitGroup
+	cert *x509.Certificate
+	err  error
+}
+
+// do calls fn for a key, unless a call for it is already in progress, in
+// which case it waits for and returns that call's result.
+func (g *flightGroup) do(key string, fn func() (*x509.Certificate, error)) (*x509.Certificate, error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*flightCall)
+	}
+
+	if c, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		c.wg.Wait()
+
+		return c.cert, c.err
+	}
+
+	c := &flightCall{}
+	c.wg.Add(1)
+	g.calls[key] = c
+	g.mu.Unlock()
+
+	defer func() {
+		c.wg.Done()
+
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+	}()
+
+	c.cert, c.err = fn()
+
+	return c.cert, c.err
+}
@@ -0,0 +1,156 @@
+package validations
+
+import (
+	"encoding/binary"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var replayBucket = []byte("requests")
+
+// pruneInterval is how often expired request IDs are removed from a
+// ReplayStore.
+const pruneInterval = time.Minute
+
+// ReplayStore records the IDs of validated requests so the same request cannot
+// be used again. It must be safe for concurrent use.
+type ReplayStore interface {
+	// Seen records a request ID until expires. It returns true if the ID was
+	// already recorded and has not expired by now.
+	Seen(requestID string, now, expires time.Time) (bool, error)
+}
+
+// MemoryReplayStore is a ReplayStore that keeps request IDs in memory.
+type MemoryReplayStore struct {
+	mu        sync.Mutex
+	ids       map[string]time.Time
+	lastPrune time.Time
+}
+
+// NewMemoryReplayStore creates a new MemoryReplayStore.
+func NewMemoryReplayStore() *MemoryReplayStore {
+	return &MemoryReplayStore{
+		ids: make(map[string]time.Time),
+	}
+}
+
+// Seen records a request ID until expires, returning if it was already
+// recorded.
+func (s *MemoryReplayStore) Seen(requestID string, now, expires time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Occasionally remove expired IDs so they don't build up forever
+	if now.Sub(s.lastPrune) >= pruneInterval {
+		for id, at := range s.ids {
+			if !now.Before(at) {
+				delete(s.ids, id)
+			}
+		}
+
+		s.lastPrune = now
+	}
+
+	if at, ok := s.ids[requestID]; ok && now.Before(at) {
+		return true, nil
+	}
+
+	s.ids[requestID] = expires
+
+	return false, nil
+}
+
+// BoltReplayStore is a ReplayStore that stores request IDs in a bolt
+// database, so they may be shared by processes and survive restarts.
+type BoltReplayStore struct {
+	db *bolt.DB
+
+	mu        sync.Mutex
+	lastPrune time.Time
+}
+
+// NewBoltReplayStore creates a new BoltReplayStore. The bucket is created when
+// the first ID is recorded.
+func NewBoltReplayStore(db *bolt.DB) *BoltReplayStore {
+	return &BoltReplayStore{db: db}
+}
+
+// Seen records a request ID until expires, returning if it was already
+// recorded.
+func (s *BoltReplayStore) Seen(requestID string, now, expires time.Time) (bool, error) {
+	prune := s.shouldPrune(now)
+
+	var seen bool
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists(replayBucket)
+		if err != nil {
+			return err
+		}
+
+		// Occasionally remove expired IDs so they don't build up forever
+		if prune {
+			var expired [][]byte
+
+			b.ForEach(func(k, v []byte) error {
+				if !now.Before(decodeExpiry(v)) {
+					expired = append(expired, append([]byte(nil), k...))
+				}
+				return nil
+			})
+
+			for _, k := range expired {
+				if err = b.Delete(k); err != nil {
+					return err
+				}
+			}
+		}
+
+		if v := b.Get([]byte(requestID)); v != nil && now.Before(decodeExpiry(v)) {
+			seen = true
+			return nil
+		}
+
+		return b.Put([]byte(requestID), encodeExpiry(expires))
+	})
+
+	return seen, err
+}
+
+// shouldPrune is if it is time to remove expired IDs.
+func (s *BoltReplayStore) shouldPrune(now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.Sub(s.lastPrune) < pruneInterval {
+		return false
+	}
+
+	s.lastPrune = now
+
+	return true
+}
+
+// Close closes the database.
+func (s *BoltReplayStore) Close() error {
+	return s.db.Close()
+}
+
+// encodeExpiry encodes when a request ID expires for storage.
+func encodeExpiry(expires time.Time) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, uint64(expires.UnixNano()))
+
+	return b
+}
+
+// decodeExpiry decodes when a stored request ID expires.
+func decodeExpiry(b []byte) time.Time {
+	if len(b) != 8 {
+		return time.Time{}
+	}
+
+	return time.Unix(0, int64(binary.BigEndian.Uint64(b)))
+}
@@ -0,0 +1,97 @@
+package validations
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/go-alexa/alexa/parser"
+)
+
+func TestReplayStores(t *testing.T) {
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "requests.db"), 0600, nil)
+	if err != nil {
+		t.Fatalf("unable to open database: %v", err)
+	}
+	defer db.Close()
+
+	now := time.Now()
+	expires := now.Add(time.Minute)
+
+	for name, store := range map[string]ReplayStore{
+		"memory": NewMemoryReplayStore(),
+		"bolt":   NewBoltReplayStore(db),
+	} {
+		for _, tt := range []struct {
+			id      string
+			now     time.Time
+			expires time.Time
+			seen    bool
+		}{
+			{"first", now, expires, false},
+			{"first", now.Add(time.Second), expires, true},
+			{"second", now.Add(time.Second), expires, false},
+			{"first", expires, expires.Add(time.Minute), false},
+			{"first", expires.Add(time.Second), expires.Add(time.Minute), true},
+		} {
+			seen, err := store.Seen(tt.id, tt.now, tt.expires)
+			if err != nil || seen != tt.seen {
+				t.Errorf("%s: expected %s at %v seen %v, got %v, %v", name, tt.id,
+					tt.now.Sub(now), tt.seen, seen, err)
+			}
+		}
+	}
+}
+
+func TestReplayStorePrunes(t *testing.T) {
+	store := NewMemoryReplayStore()
+	now := time.Now()
+
+	store.Seen("old", now, now.Add(time.Second))
+	store.Seen("new", now.Add(2*pruneInterval), now.Add(3*pruneInterval))
+
+	if _, ok := store.ids["old"]; ok {
+		t.Error("expected the expired ID to be removed")
+	}
+
+	if _, ok := store.ids["new"]; !ok {
+		t.Error("expected the new ID to be kept")
+	}
+}
+
+func TestValidateRequestReplay(t *testing.T) {
+	now := time.Now()
+	ts := parser.Time(now)
+
+	v := NewValidator("app")
+	v.Replay = NewMemoryReplayStore()
+	v.Clock = func() time.Time { return now }
+
+	ev := &parser.Event{
+		Session: parser.Session{Application: parser.Application{ID: "app"}},
+		Request: parser.Request{ID: "request", Timestamp: &ts},
+	}
+
+	if err := v.ValidateRequest(ev); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	err := v.ValidateRequest(ev)
+	if !errors.Is(err, ErrReplayed) {
+		t.Fatalf("expected %v, got %v", ErrReplayed, err)
+	}
+
+	var verr *ValidationError
+	if !errors.As(err, &verr) || verr.Stage != StageReplay {
+		t.Errorf("expected a %s error, got %v", StageReplay, err)
+	}
+
+	// Once it is outside the time limit, it is rejected for that instead
+	now = now.Add(time.Duration(v.TimeLimit+1) * time.Second)
+	if err = v.ValidateRequest(ev); !errors.Is(err, ErrOutsideTime) {
+		t.Errorf("expected %v, got %v", ErrOutsideTime, err)
+	}
+}
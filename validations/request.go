@@ -1,27 +1,56 @@
 package validations
 
 import (
-	"errors"
 	"math"
 	"time"
 
 	"github.com/go-alexa/alexa/parser"
 )
 
-var (
-	errOutsideTime = errors.New("timestamp difference was greater than allowed")
-	errWrongApp    = errors.New("application IDs do not match")
-)
-
 // ValidateRequest ensures the request was made within TimeLimit and was for
 // this AppID.
 func ValidateRequest(ev *parser.Event) error {
-	if math.Abs(time.Since(ev.Request.Timestamp.ToTime()).Seconds()) > TimeLimit {
-		return errOutsideTime
+	v := Validator{
+		AppID:     AppID,
+		TimeLimit: TimeLimit,
+	}
+
+	return v.ValidateRequest(ev)
+}
+
+// ValidateRequest ensures the request was made within the Validator's
+// TimeLimit and was for its AppID. If the Validator has a Replay store, it also
+// ensures the request has not already been validated.
+func (v *Validator) ValidateRequest(ev *parser.Event) error {
+	now := v.now()
+
+	// A request without a timestamp could have been made at any time
+	if ev.Request.Timestamp == nil {
+		return newError(StageTimestamp, ErrOutsideTime, "")
 	}
 
-	if ev.Session.Application.ID != AppID {
-		return errWrongApp
+	timestamp := ev.Request.Timestamp.ToTime()
+	if math.Abs(now.Sub(timestamp).Seconds()) > v.TimeLimit {
+		return newError(StageTimestamp, ErrOutsideTime, timestamp.Format(time.RFC3339))
+	}
+
+	if appID := ev.ApplicationID(); appID != v.AppID {
+		return newError(StageApplication, ErrWrongApp, appID)
+	}
+
+	// The timestamp check rejects it once it is outside TimeLimit, so the ID
+	// only needs to be remembered until then
+	if v.Replay != nil && ev.Request.ID != "" {
+		expires := timestamp.Add(time.Duration(v.TimeLimit * float64(time.Second)))
+
+		seen, err := v.Replay.Seen(ev.Request.ID, now, expires)
+		if err != nil {
+			return newError(StageReplay, err, "")
+		}
+
+		if seen {
+			return newError(StageReplay, ErrReplayed, ev.Request.ID)
+		}
 	}
 
 	return nil
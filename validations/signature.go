@@ -2,7 +2,6 @@ package validations
 
 import (
 	"bytes"
-	"errors"
 
 	"io/ioutil"
 
@@ -12,37 +11,84 @@ import (
 	"encoding/base64"
 )
 
-var (
-	errNoSignature = errors.New("unable to find signature header")
+const (
+	// signature256Header is the header of the SHA-256 signature.
+	signature256Header = "Signature-256"
+	// signatureHeader is the header of the legacy SHA-1 signature.
+	signatureHeader = "Signature"
 )
 
+// AllowSHA1 allows the package level functions to verify requests with the
+// legacy SHA-1 signature when there is no SHA-256 signature. It is off by
+// default, as otherwise removing the SHA-256 signature downgrades the check,
+// and must be set before any requests are validated to opt in.
+var AllowSHA1 bool
+
 // ValidateSignature ensures that the request body was made with the
 // certificate provided in the header. Returns the body as it has to be read
 // here and it prevents unneeded code to read it again.
 func ValidateSignature(r *http.Request, cert *x509.Certificate) ([]byte, error) {
+	body, _, err := ValidateSignatureAlgorithm(r, cert)
+	return body, err
+}
+
+// ValidateSignatureAlgorithm is the same as ValidateSignature, but also
+// returns which algorithm verified the request. The SHA-256 signature is
+// preferred, falling back to SHA-1 if AllowSHA1 is set.
+func ValidateSignatureAlgorithm(r *http.Request, cert *x509.Certificate) ([]byte, x509.SignatureAlgorithm, error) {
+	return validateSignature(r, cert, AllowSHA1)
+}
+
+// ValidateSignature ensures that the request body was made with the
+// certificate provided in the header.
+func (v *Validator) ValidateSignature(r *http.Request, cert *x509.Certificate) ([]byte, error) {
+	body, _, err := v.ValidateSignatureAlgorithm(r, cert)
+	return body, err
+}
+
+// ValidateSignatureAlgorithm is the same as ValidateSignature, but also
+// returns which algorithm verified the request. The SHA-256 signature is
+// preferred, falling back to SHA-1 only if the Validator allows it.
+func (v *Validator) ValidateSignatureAlgorithm(r *http.Request, cert *x509.Certificate) ([]byte, x509.SignatureAlgorithm, error) {
+	return validateSignature(r, cert, v.AllowSHA1)
+}
+
+// validateSignature verifies the request body with the best signature
+// available.
+func validateSignature(r *http.Request, cert *x509.Certificate, allowSHA1 bool) ([]byte, x509.SignatureAlgorithm, error) {
 	// First, get the signature from the headers
-	sig, err := getSignature(r)
+	sig, alg, err := getSignature(r, allowSHA1)
 	if err != nil {
-		return nil, err
+		return nil, x509.UnknownSignatureAlgorithm, err
 	}
 
 	// Then get the request body
 	body, err := getBody(r)
 	if err != nil {
-		return nil, err
+		return nil, x509.UnknownSignatureAlgorithm, newError(StageBody, err, "")
 	}
 
 	// Return if the signature properly verified
-	return body, verifySignature(sig, body, cert)
+	if err = verifySignature(sig, alg, body, cert); err != nil {
+		return nil, x509.UnknownSignatureAlgorithm, err
+	}
+
+	return body, alg, nil
 }
 
-// getSignature attempts to get the signature from the headers.
-func getSignature(r *http.Request) (string, error) {
-	sig := r.Header.Get("Signature")
-	if sig == "" {
-		return sig, errNoSignature
+// getSignature attempts to get the signature from the headers, along with
+// the algorithm it was made with. A SHA-1 signature is only used when there
+// is no SHA-256 signature and it is allowed.
+func getSignature(r *http.Request, allowSHA1 bool) (string, x509.SignatureAlgorithm, error) {
+	if sig := r.Header.Get(signature256Header); sig != "" {
+		return sig, x509.SHA256WithRSA, nil
 	}
-	return sig, nil
+
+	if sig := r.Header.Get(signatureHeader); sig != "" && allowSHA1 {
+		return sig, x509.SHA1WithRSA, nil
+	}
+
+	return "", x509.UnknownSignatureAlgorithm, newError(StageSignature, ErrNoSignature, "")
 }
 
 // getBody gets the contents of the body as a byte array and returns it.
@@ -57,11 +103,15 @@ func getBody(r *http.Request) ([]byte, error) {
 }
 
 // verifySignature actually checks that the body matches the signature.
-func verifySignature(sig string, body []byte, cert *x509.Certificate) error {
+func verifySignature(sig string, alg x509.SignatureAlgorithm, body []byte, cert *x509.Certificate) error {
 	decodedSig, err := base64.StdEncoding.DecodeString(sig)
 	if err != nil {
-		return err
+		return newError(StageSignature, ErrBadSignature, err.Error())
+	}
+
+	if err = cert.CheckSignature(alg, body, decodedSig); err != nil {
+		return newError(StageSignature, ErrBadSignature, alg.String())
 	}
 
-	return cert.CheckSignature(x509.SHA1WithRSA, body, decodedSig)
+	return nil
 }
@@ -0,0 +1,69 @@
+package validations
+
+import (
+	"crypto/x509"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestValidateSignatureAlgorithm(t *testing.T) {
+	chain := newTestChain(t, time.Now().Add(48*time.Hour))
+	other := newTestChain(t, time.Now().Add(48*time.Hour))
+	body := []byte(`{"version":"1.0"}`)
+
+	for _, tt := range []struct {
+		name      string
+		sig256    string
+		sig1      string
+		allowSHA1 bool
+		alg       x509.SignatureAlgorithm
+	}{
+		{"SHA-256", chain.sign(t, body), "", false, x509.SHA256WithRSA},
+		{"SHA-256 preferred", chain.sign(t, body), other.signSHA1(t, body), true, x509.SHA256WithRSA},
+		{"SHA-1 allowed", "", chain.signSHA1(t, body), true, x509.SHA1WithRSA},
+		{"SHA-1 not allowed", "", chain.signSHA1(t, body), false, x509.UnknownSignatureAlgorithm},
+		{"bad SHA-256 without fallback", other.sign(t, body), chain.signSHA1(t, body), true, x509.UnknownSignatureAlgorithm},
+		{"none", "", "", true, x509.UnknownSignatureAlgorithm},
+	} {
+		v := NewValidator("app")
+		v.AllowSHA1 = tt.allowSHA1
+
+		r := newTestRequest(body, tt.sig256)
+		if tt.sig256 == "" {
+			r.Header.Del("Signature-256")
+		}
+		if tt.sig1 != "" {
+			r.Header.Set("Signature", tt.sig1)
+		}
+
+		got, alg, err := v.ValidateSignatureAlgorithm(r, chain.cert)
+		if alg != tt.alg {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.alg, alg)
+		}
+
+		if tt.alg == x509.UnknownSignatureAlgorithm {
+			if err == nil {
+				t.Errorf("%s: expected an error", tt.name)
+			}
+			continue
+		}
+
+		if err != nil || string(got) != string(body) {
+			t.Errorf("%s: expected the body, got %q, %v", tt.name, got, err)
+		}
+	}
+}
+
+func TestPackageLevelRejectsSHA1(t *testing.T) {
+	chain := newTestChain(t, time.Now().Add(48*time.Hour))
+	body := []byte(`{"version":"1.0"}`)
+
+	r := newTestRequest(body, "")
+	r.Header.Del("Signature-256")
+	r.Header.Set("Signature", chain.signSHA1(t, body))
+
+	if _, err := ValidateSignature(r, chain.cert); !errors.Is(err, ErrNoSignature) {
+		t.Errorf("expected %v by default, got %v", ErrNoSignature, err)
+	}
+}
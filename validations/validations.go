@@ -3,16 +3,147 @@
 package validations
 
 import (
-	"github.com/boltdb/bolt"
+	"crypto/x509"
+	"net/http"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
 )
 
-// DB must be set to a valid database in order to properly cache certificates
-// instead of downloading them for every request.
+// DB may be set to a valid database in order to cache certificates across
+// restarts instead of only in memory. It must be set before any requests are
+// validated, as it is read without synchronization. Use SetDB to change it
+// once requests may be validated.
 var DB *bolt.DB
 
+// SetDB sets DB. It is safe to call while requests are being validated, which
+// use the new database once it returns.
+func SetDB(db *bolt.DB) {
+	stdMu.Lock()
+	defer stdMu.Unlock()
+
+	DB = db
+	std = nil
+}
+
+// SetRoots sets the roots signing certificates must chain to for the package
+// level functions. If nil, the system's roots are used. Like SetDB, it is safe
+// to call while requests are being validated.
+func SetRoots(roots *x509.CertPool) {
+	stdMu.Lock()
+	defer stdMu.Unlock()
+
+	stdRoots = roots
+	std = nil
+}
+
 // TimeLimit is the maximum variance allowed in the timestamp from current time.
 // It must be under 150 seconds to submit to Amazon.
 var TimeLimit float64 = 60
 
 // AppID is your Skill's ID. It must be set to verify App ID in requests.
 var AppID string
+
+// DefaultRefreshBefore is how long before a signing certificate expires that
+// its chain is downloaded again.
+const DefaultRefreshBefore = 24 * time.Hour
+
+// refreshRetry is how long to wait between attempts to refresh a chain that is
+// nearly expired.
+const refreshRetry = time.Hour
+
+// Validator holds everything needed to validate a request. Unlike the package
+// level functions, it does not depend on any global state so multiple
+// Validators may be used within the same process.
+//
+// A Validator is safe for concurrent use. Its fields must not be changed once
+// it has been used.
+type Validator struct {
+	// AppID is the Skill's ID. Requests for any other ID are rejected.
+	AppID string
+	// TimeLimit is the maximum variance allowed in the timestamp, in seconds.
+	TimeLimit float64
+	// Cache is used to cache certificate chains. If nil, they are downloaded
+	// for every request.
+	Cache CertCache
+	// RefreshBefore is how long before a signing certificate expires that its
+	// chain is downloaded again. If zero, chains are only downloaded again
+	// once they fail to verify.
+	RefreshBefore time.Duration
+	// Client is used to download certificate chains. If nil, a client with a
+	// timeout of DefaultDownloadTimeout is used.
+	Client *http.Client
+	// AllowSHA1 allows requests to be verified with the legacy SHA-1
+	// signature when there is no SHA-256 signature.
+	AllowSHA1 bool
+	// Roots are the certificates signing certificates must chain to. If nil,
+	// the system's roots are used.
+	Roots *x509.CertPool
+	// Replay records the IDs of validated requests so they are rejected if
+	// they are sent again within TimeLimit. If nil, requests are not checked
+	// for replays.
+	Replay ReplayStore
+	// Clock gets the current time. If nil, time.Now is used.
+	Clock func() time.Time
+
+	// verified are signing certificates that have already been verified.
+	verified verifiedCerts
+	// fetches are the certificate chains currently being downloaded.
+	fetches flightGroup
+}
+
+// NewValidator creates a new Validator for an AppID with the default
+// TimeLimit, caching certificate chains in memory.
+func NewValidator(appID string) *Validator {
+	return &Validator{
+		AppID:     appID,
+		TimeLimit: 60,
+		Cache:     NewMemoryCertCache(),
+
+		RefreshBefore: DefaultRefreshBefore,
+	}
+}
+
+// now gets the current time from the Validator's Clock.
+func (v *Validator) now() time.Time {
+	if v.Clock != nil {
+		return v.Clock()
+	}
+
+	return time.Now()
+}
+
+var (
+	// stdMu protects std and stdRoots, and DB once requests may be validated.
+	stdMu sync.Mutex
+	// std is the Validator used by the package level functions.
+	std *Validator
+	// stdRoots are the roots set by SetRoots.
+	stdRoots *x509.CertPool
+)
+
+// standard gets the Validator used by the package level functions. It is
+// created once, and again only after SetDB or SetRoots, so it is never
+// modified while requests may be using it.
+func standard() *Validator {
+	stdMu.Lock()
+	defer stdMu.Unlock()
+
+	if std != nil {
+		return std
+	}
+
+	var cache CertCache = NewMemoryCertCache()
+	if DB != nil {
+		cache = NewBoltCertCache(DB)
+	}
+
+	std = &Validator{
+		Cache:         cache,
+		RefreshBefore: DefaultRefreshBefore,
+		Roots:         stdRoots,
+	}
+
+	return std
+}
@@ -0,0 +1,192 @@
+package validations
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// newCertServer starts a local TLS server that serves a chain for every
+// request. The returned Client sends every request to it, whatever the host.
+func newCertServer(t *testing.T, chain []byte, downloads *int32) *http.Client {
+	t.Helper()
+
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(downloads, 1)
+		w.Write(chain)
+	}))
+	t.Cleanup(srv.Close)
+
+	client := srv.Client()
+	transport := client.Transport.(*http.Transport).Clone()
+	transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		var d net.Dialer
+		return d.DialContext(ctx, network, srv.Listener.Addr().String())
+	}
+
+	// The test server's certificate is for example.com
+	transport.TLSClientConfig = transport.TLSClientConfig.Clone()
+	transport.TLSClientConfig.ServerName = "example.com"
+
+	client.Transport = transport
+
+	return client
+}
+
+// validateParallel validates many signed requests at once with validate.
+func validateParallel(t *testing.T, chain *testChain, validate func(*http.Request) error) {
+	t.Helper()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 50)
+
+	for i := 0; i < cap(errs); i++ {
+		r := chain.request(t, []byte(`{"version":"1.0"}`))
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- validate(r)
+		}()
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+}
+
+func TestValidatorParallel(t *testing.T) {
+	chain := newTestChain(t, time.Now().Add(48*time.Hour))
+
+	var downloads int32
+
+	v := NewValidator("app")
+	v.Roots = testRoots(t)
+	v.Client = newCertServer(t, chain.pem, &downloads)
+
+	validateParallel(t, chain, func(r *http.Request) error {
+		cert, err := v.ValidateCertificate(r)
+		if err != nil {
+			return err
+		}
+
+		_, err = v.ValidateSignature(r, cert)
+		return err
+	})
+
+	if downloads != 1 {
+		t.Errorf("expected 1 download, got %d", downloads)
+	}
+}
+
+func TestPackageLevelParallel(t *testing.T) {
+	chain := newTestChain(t, time.Now().Add(48*time.Hour))
+
+	var downloads int32
+
+	oldClient := defaultClient
+	defer func() {
+		defaultClient = oldClient
+		SetRoots(nil)
+		SetDB(nil)
+	}()
+
+	// Trust the test root, as the package level functions use the system's
+	defaultClient = newCertServer(t, chain.pem, &downloads)
+	SetRoots(testRoots(t))
+
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "certs.db"), 0600, nil)
+	if err != nil {
+		t.Fatalf("unable to open database: %v", err)
+	}
+	defer db.Close()
+
+	SetDB(db)
+
+	validateParallel(t, chain, func(r *http.Request) error {
+		cert, err := ValidateCertificate(r)
+		if err != nil {
+			return err
+		}
+
+		_, err = ValidateSignature(r, cert)
+		return err
+	})
+
+	if downloads != 1 {
+		t.Errorf("expected 1 download, got %d", downloads)
+	}
+
+	entry, err := NewBoltCertCache(db).Get(testChainURL)
+	if err != nil || entry == nil {
+		t.Errorf("expected the chain to be cached in DB, got %+v, %v", entry, err)
+	}
+}
+
+func TestSetDBParallel(t *testing.T) {
+	chain := newTestChain(t, time.Now().Add(48*time.Hour))
+
+	var downloads int32
+
+	oldClient := defaultClient
+	defer func() {
+		defaultClient = oldClient
+		SetRoots(nil)
+		SetDB(nil)
+	}()
+
+	defaultClient = newCertServer(t, chain.pem, &downloads)
+	SetRoots(testRoots(t))
+
+	var dbs []*bolt.DB
+	for _, name := range []string{"a.db", "b.db"} {
+		db, err := bolt.Open(filepath.Join(t.TempDir(), name), 0600, nil)
+		if err != nil {
+			t.Fatalf("unable to open database: %v", err)
+		}
+		defer db.Close()
+
+		dbs = append(dbs, db)
+	}
+
+	// Swap the database while requests are being validated
+	stop := make(chan struct{})
+	swapped := make(chan struct{})
+	go func() {
+		defer close(swapped)
+		for i := 0; ; i++ {
+			select {
+			case <-stop:
+				return
+			default:
+				SetDB(dbs[i%len(dbs)])
+			}
+		}
+	}()
+
+	validateParallel(t, chain, func(r *http.Request) error {
+		cert, err := ValidateCertificate(r)
+		if err != nil {
+			return err
+		}
+
+		_, err = ValidateSignature(r, cert)
+		return err
+	})
+
+	close(stop)
+	<-swapped
+}
@@ -0,0 +1,60 @@
+package validations
+
+import (
+	"crypto/x509"
+	"sync"
+	"time"
+)
+
+// verifiedCerts is an in-memory cache of signing certificates that have
+// already been verified, keyed by their chain URL. It lets requests skip
+// parsing and verifying the chain again, leaving only the signature check.
+type verifiedCerts struct {
+	mu    sync.RWMutex
+	certs map[string]verifiedCert
+}
+
+// verifiedCert is a verified certificate and when it should be checked again.
+type verifiedCert struct {
+	cert      *x509.Certificate
+	refreshAt time.Time
+}
+
+// get gets a verified certificate if it has not expired or needs a refresh
+// by now.
+func (c *verifiedCerts) get(chainURL string, now time.Time) *x509.Certificate {
+	c.mu.RLock()
+	v, ok := c.certs[chainURL]
+	c.mu.RUnlock()
+
+	if !ok {
+		return nil
+	}
+
+	if now.After(v.cert.NotAfter) || !now.Before(v.refreshAt) {
+		c.mu.Lock()
+		if c.certs[chainURL] == v {
+			delete(c.certs, chainURL)
+		}
+		c.mu.Unlock()
+
+		return nil
+	}
+
+	return v.cert
+}
+
+// put stores a verified certificate until refreshAt.
+func (c *verifiedCerts) put(chainURL string, cert *x509.Certificate, refreshAt time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.certs == nil {
+		c.certs = make(map[string]verifiedCert)
+	}
+
+	c.certs[chainURL] = verifiedCert{
+		cert:      cert,
+		refreshAt: refreshAt,
+	}
+}
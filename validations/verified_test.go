@@ -0,0 +1,85 @@
+package validations
+
+import (
+	"testing"
+	"time"
+)
+
+func TestVerifiedCertsEviction(t *testing.T) {
+	chain := newTestChain(t, time.Now().Add(time.Hour))
+
+	var c verifiedCerts
+	c.put(testChainURL, chain.cert, chain.cert.NotAfter)
+
+	if got := c.get(testChainURL, time.Now()); got != chain.cert {
+		t.Errorf("expected cached certificate, got %v", got)
+	}
+
+	if got := c.get(testChainURL, chain.cert.NotAfter.Add(time.Second)); got != nil {
+		t.Errorf("expected expired certificate to be evicted, got %v", got)
+	}
+
+	if got := c.get(testChainURL, time.Now()); got != nil {
+		t.Errorf("expected evicted certificate to stay evicted, got %v", got)
+	}
+}
+
+func TestValidateCertificateUsesVerified(t *testing.T) {
+	chain := newTestChain(t, time.Now().Add(48*time.Hour))
+	v := newTestValidator(t, chain)
+
+	first, err := v.ValidateCertificate(chain.request(t, []byte("{}")))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Even if the cached chain is gone, the verified certificate is used
+	v.Cache = NewMemoryCertCache()
+
+	second, err := v.ValidateCertificate(chain.request(t, []byte("{}")))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if first != second {
+		t.Error("expected the same parsed certificate to be returned")
+	}
+}
+
+// benchmarkValidate validates a signed request, optionally starting each
+// iteration without any verified certificates.
+func benchmarkValidate(b *testing.B, cold bool) {
+	chain := newTestChain(b, time.Now().Add(48*time.Hour))
+	v := newTestValidator(b, chain)
+
+	body := []byte(`{"version":"1.0"}`)
+	sig := chain.sign(b, body)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if cold {
+			v.verified = verifiedCerts{}
+		}
+
+		r := newTestRequest(body, sig)
+
+		cert, err := v.ValidateCertificate(r)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		if _, err = v.ValidateSignature(r, cert); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkValidateCold(b *testing.B) {
+	benchmarkValidate(b, true)
+}
+
+func BenchmarkValidateVerified(b *testing.B) {
+	benchmarkValidate(b, false)
+}